@@ -0,0 +1,13 @@
+package mplus
+
+import (
+	"github.com/tangzixiang/mplus/app"
+)
+
+type App = app.App
+type RouteInfo = app.RouteInfo
+
+var (
+	NewApp     = app.New
+	DefaultApp = app.Default
+)
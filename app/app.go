@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tangzixiang/mplus/errs"
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/message"
+	"github.com/tangzixiang/mplus/mhttp"
+	"github.com/tangzixiang/mplus/middleware"
+	"github.com/tangzixiang/mplus/validate"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// App 实例级配置，汇总 mhttp、validate、errs、header 及 middleware 的配置，同一进程内的不同 App 可以拥有互不影响的配置，
+// 通过 route.Route.WithApp 或 Middleware 注入请求上下文后，当前请求将使用 App 中的配置
+type App struct {
+	HTTP        *mhttp.Config
+	Validate    *validate.Config
+	Errs        *errs.Config
+	Header      *header.Config
+	Middlewares *middleware.Config
+
+	routesLock sync.Mutex
+	routes     []RouteInfo
+	routeSet   map[string]bool
+}
+
+// RouteInfo 路由的访问控制信息，用于审计路由声明的权限
+type RouteInfo struct {
+	// Pattern 路由模式，如 /orders/{id}
+	Pattern string
+	// Requirements 路由声明的角色、权限范围或权限，为空时路由未声明权限
+	Requirements []string
+}
+
+// Default 默认实例，包级别的 SetDefaultMemorySize、SetStrictJSONBodyCheck、SetEnvelope、SetTrustedProxies、
+// RegisterHttpStatusMethod、RegisterValidateErrorFunc 等函数均作用于该实例，未注入 App 的请求使用该实例
+var Default = &App{
+	HTTP:        mhttp.DefaultConfig,
+	Validate:    validate.DefaultConfig,
+	Errs:        errs.DefaultConfig,
+	Header:      header.DefaultConfig,
+	Middlewares: middleware.DefaultConfig,
+}
+
+// New 获取一个新的 App 实例，解析错误处理器初始为 Default 中已注册处理器的拷贝，其余配置为初始值
+func New() *App {
+	return &App{
+		HTTP:        mhttp.NewConfig(),
+		Validate:    validate.NewConfig(),
+		Errs:        errs.NewConfig(),
+		Header:      header.NewConfig(),
+		Middlewares: middleware.NewConfig(),
+	}
+}
+
+// SetDefaultLang 设置无法确定请求语言时使用的语言
+func (a *App) SetDefaultLang(lang message.MSGType) {
+	a.HTTP.SetDefaultLang(lang)
+}
+
+// SetDefaultMemorySize 设置 http.Request.ParseMultipartForm 参数 maxMemory
+func (a *App) SetDefaultMemorySize(size int64) {
+	a.HTTP.SetDefaultMemorySize(size)
+}
+
+// SetMaxDecompressSize 设置携带 Content-Encoding 的请求体压缩的内容及解压后的内容允许的最大字节数
+func (a *App) SetMaxDecompressSize(size int64) {
+	a.HTTP.SetMaxDecompressSize(size)
+}
+
+// SetEnvelope 设置响应信封，为 nil 时不对响应数据进行包装
+func (a *App) SetEnvelope(f mhttp.EnvelopeFunc) {
+	a.HTTP.SetEnvelope(f)
+}
+
+// SetProblemDetails 设置是否以 RFC 7807 application/problem+json 格式响应异常信息
+func (a *App) SetProblemDetails(enable bool) {
+	a.HTTP.SetProblemDetails(enable)
+}
+
+// SetProblemType 设置问题类型 URI 的生成方式
+func (a *App) SetProblemType(f mhttp.ProblemTypeFunc) {
+	a.HTTP.SetProblemType(f)
+}
+
+// SetLangQueryKey 设置用于指定请求语言的 query 参数名，为空时不从 query 中获取
+func (a *App) SetLangQueryKey(key string) {
+	a.HTTP.SetLangQueryKey(key)
+}
+
+// SetLangCookieName 设置用于指定请求语言的 cookie 名，为空时不从 cookie 中获取
+func (a *App) SetLangCookieName(name string) {
+	a.HTTP.SetLangCookieName(name)
+}
+
+// SetSupportedLangs 设置项目支持的语言
+func (a *App) SetSupportedLangs(langs ...message.MSGType) {
+	a.HTTP.SetSupportedLangs(langs...)
+}
+
+// SetTrustedProxies 设置受信任的代理，支持 CIDR 及单个 IP
+func (a *App) SetTrustedProxies(proxies ...string) error {
+	return a.Header.SetTrustedProxies(proxies...)
+}
+
+// SetRecoverLogger 设置 Recover 中间件记录 panic 的方式，为 nil 时不记录
+func (a *App) SetRecoverLogger(logger middleware.RecoverLogger) {
+	a.Middlewares.SetRecoverLogger(logger)
+}
+
+// SetErrorLogger 设置 HandleError 遇到未知异常时的记录方式，为 nil 时不记录
+func (a *App) SetErrorLogger(logger middleware.ErrorLogger) {
+	a.Middlewares.SetErrorLogger(logger)
+}
+
+// SetAccessLogSink 设置访问日志中间件未指定输出方式时使用的输出方式，为 nil 时不记录
+func (a *App) SetAccessLogSink(sink middleware.AccessLogSink) {
+	a.Middlewares.SetAccessLogSink(sink)
+}
+
+// SetAuthorizePolicy 设置授权中间件未指定策略时使用的默认授权策略，为 nil 时恢复为 middleware.AllScopesPolicy
+func (a *App) SetAuthorizePolicy(policy middleware.AuthorizePolicy) {
+	a.Middlewares.SetAuthorizePolicy(policy)
+}
+
+// RegisterHttpStatusMethod 注册请求状态回调
+func (a *App) RegisterHttpStatusMethod(statusCode int, f mhttp.StatusMethodCallback) {
+	a.HTTP.RegisterHttpStatusMethod(statusCode, f)
+}
+
+// RegisterHttpStatusClassMethod 注册状态码类别的请求状态回调，状态码不存在精确匹配的回调时使用
+func (a *App) RegisterHttpStatusClassMethod(class mhttp.StatusClass, f mhttp.StatusMethodCallback) {
+	a.HTTP.RegisterHttpStatusClassMethod(class, f)
+}
+
+// RegisterHttpStatusFallbackMethod 注册兜底的请求状态回调，4xx/5xx 状态码不存在精确匹配及类别匹配的回调时使用
+func (a *App) RegisterHttpStatusFallbackMethod(f mhttp.StatusMethodCallback) {
+	a.HTTP.RegisterHttpStatusFallbackMethod(f)
+}
+
+// SetStrictJSONBodyCheck 设置是否严格校验 json 请求
+func (a *App) SetStrictJSONBodyCheck(b bool) {
+	a.Validate.SetStrictJSONBodyCheck(b)
+}
+
+// SetValidator 设置校验器，为 nil 时使用全局的 validate.Validate
+func (a *App) SetValidator(v *validator.Validate) {
+	a.Validate.SetValidator(v)
+}
+
+// RegisterValidateErrorFunc 注册一个解析失败的处理器
+func (a *App) RegisterValidateErrorFunc(errType errs.ValidateErrorType, fun errs.ValidateErrorFunc) {
+	a.Errs.RegisterValidateErrorFunc(errType, fun)
+}
+
+// RegisterGlobalValidateErrorHandler 注册全局 ValidateError 异常处理器，注册后，指定类型的 ValidateError handler 将不在执行
+func (a *App) RegisterGlobalValidateErrorHandler(fun errs.ValidateErrorFunc) {
+	a.Errs.RegisterGlobalValidateErrorHandler(fun)
+}
+
+// RegisterRoute 记录使用当前 App 的路由，相同的路由只记录一次，route.Route 生成 handler 时会自动记录设置了路由模式或声明了权限的路由
+func (a *App) RegisterRoute(info RouteInfo) {
+	key := info.Pattern + "\n" + strings.Join(info.Requirements, ",")
+	info.Requirements = append([]string(nil), info.Requirements...)
+
+	a.routesLock.Lock()
+	defer a.routesLock.Unlock()
+
+	if a.routeSet == nil {
+		a.routeSet = map[string]bool{}
+	}
+
+	if !a.routeSet[key] {
+		a.routeSet[key] = true
+		a.routes = append(a.routes, info)
+	}
+}
+
+// Routes 按记录顺序获取使用当前 App 的路由，用于审计各路由声明的权限
+func (a *App) Routes() []RouteInfo {
+	a.routesLock.Lock()
+	defer a.routesLock.Unlock()
+
+	routes := make([]RouteInfo, 0, len(a.routes))
+	for _, info := range a.routes {
+		info.Requirements = append([]string(nil), info.Requirements...)
+		routes = append(routes, info)
+	}
+
+	return routes
+}
+
+// WithContext 将 App 的配置注入至 ctx
+func (a *App) WithContext(ctx context.Context) context.Context {
+	ctx = mhttp.WithConfig(ctx, a.HTTP)
+	ctx = validate.WithConfig(ctx, a.Validate)
+	ctx = header.WithConfig(ctx, a.Header)
+	ctx = middleware.WithConfig(ctx, a.Middlewares)
+	return errs.WithConfig(ctx, a.Errs)
+}
+
+// Middleware 将 App 的配置注入请求上下文的中间件
+func (a *App) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(a.WithContext(r.Context())))
+	}
+}
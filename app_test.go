@@ -0,0 +1,127 @@
+package mplus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestApp(t *testing.T) {
+	publicApp := NewApp()
+	publicApp.SetStrictJSONBodyCheck(true)
+	publicApp.RegisterHttpStatusMethod(http.StatusBadRequest, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte("public"))
+	})
+
+	adminApp := NewApp()
+	adminApp.RegisterValidateErrorFunc(ErrBodyValidate, func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte("admin"))
+	})
+
+	assert.False(t, StrictJSONBodyCheck())
+	assert.True(t, publicApp.Validate.StrictJSONBodyCheck())
+	assert.Equal(t, DefaultApp, MRote().App())
+
+	handler := func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).OK() }
+
+	tests := []struct {
+		name   string
+		route  *Route
+		status int
+		body   string
+	}{
+		{name: "public", route: MRote().WithApp(publicApp).Bind(&User{}), status: http.StatusBadRequest, body: "public"},
+		{name: "admin", route: MRote().WithApp(adminApp).Bind(&User{}), status: http.StatusUnprocessableEntity, body: "admin"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for i := 0; i < 20; i++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+				SetRequestHeader(r, HeaderContentType, MIMEJSON)
+
+				tt.route.HandlerFunc(handler).ServeHTTP(w, r)
+
+				assert.Equal(t, tt.status, w.Code)
+				assert.Equal(t, tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestApp_Settings(t *testing.T) {
+	envelopeApp := NewApp()
+	envelopeApp.SetEnvelope(DefaultEnvelope)
+	envelopeApp.SetLangQueryKey("locale")
+	envelopeApp.SetSupportedLangs(MSGLangZH)
+	assert.Nil(t, envelopeApp.SetTrustedProxies("10.0.0.0/8"))
+
+	// 获取的配置为拷贝，修改不会影响配置
+	langs := envelopeApp.HTTP.SupportedLangs()
+	langs[0] = MSGLangEN
+	assert.Equal(t, []MSGType{MSGLangZH}, envelopeApp.HTTP.SupportedLangs())
+
+	original := ValidateErrorHub[ErrDefault]
+	delete(ValidateErrorHub, ErrDefault)
+	fun, exists := NewApp().Errs.ValidateErrorHandler(ErrDefault)
+	ValidateErrorHub[ErrDefault] = original
+	assert.True(t, exists && fun != nil)
+
+	problemApp := NewApp()
+	problemApp.SetProblemDetails(true)
+
+	assert.Nil(t, Envelope())
+	assert.False(t, ProblemDetails())
+
+	serve := func(route *Route, target string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.RemoteAddr = "10.0.0.2:1234"
+		SetRequestHeader(r, HeaderForwardedFor, "1.1.1.1")
+		route.HandlerFunc(handler).ServeHTTP(w, r)
+		return w
+	}
+
+	done := NewMessage(http.StatusOK, "done").AddI18Message(MSGLangZH, "完成")
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).JSONMsg(done, map[string]string{"ip": GetClientIP(r)})
+	}
+	notFound := func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).NotFound() }
+
+	w := serve(MRote().WithApp(envelopeApp), "/?locale=zh", ok)
+	assert.JSONEq(t, `{"code":0,"message":"完成","data":{"ip":"1.1.1.1"}}`, w.Body.String())
+
+	w = serve(MRote().WithApp(problemApp), "/?locale=zh", ok)
+	assert.JSONEq(t, `{"ip":"10.0.0.2"}`, w.Body.String())
+
+	w = serve(MRote().WithApp(problemApp), "/", notFound)
+	assert.Equal(t, MIMEProblemJSON, w.Header().Get(HeaderContentType))
+
+	w = serve(MRote(), "/", notFound)
+	assert.NotEqual(t, MIMEProblemJSON, w.Header().Get(HeaderContentType))
+
+	// 配置的修改与请求的处理可以并发进行
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for i := 0; i < 50; i++ {
+			envelopeApp.SetDefaultMemorySize(int64(i))
+			envelopeApp.SetStrictJSONBodyCheck(i%2 == 0)
+			envelopeApp.SetProblemDetails(i%2 == 0)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		serve(MRote().WithApp(envelopeApp), "/", ok)
+	}
+	<-finished
+}
@@ -2,6 +2,7 @@ package context
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -13,6 +14,21 @@ type Context *map[string]interface{}
 
 const requestKey contextKey = iota
 
+// store 上下文空间，读写均需加锁，请求链中的 goroutine（如超时中间件中仍在执行的 handler）可以安全地并发读写
+type store struct {
+	lock sync.RWMutex
+	data Context
+}
+
+func newStore(data map[string]interface{}) *store {
+	return &store{data: Context(&data)}
+}
+
+func storeOf(ctx context.Context) *store {
+	s, _ := ctx.Value(requestKey).(*store)
+	return s
+}
+
 // 内部使用请求上下文键值
 const (
 	// ReqData 用于获取校验通过后缓存于上下文的 model 对象
@@ -24,47 +40,53 @@ const (
 
 // GetContextValue 从上下文中获取数据
 func GetContextValue(ctx context.Context, key string) interface{} {
-	rv := ctx.Value(requestKey)
+	s := storeOf(ctx)
 
-	if rv == nil {
+	if s == nil {
 		return nil
 	}
 
-	return (*rv.(Context))[key]
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return (*s.data)[key]
 }
 
 // SetContextValue 在上下文中添加信息
 func SetContextValue(ctx context.Context, key string, value interface{}) context.Context {
-	rv := ctx.Value(requestKey)
+	s := storeOf(ctx)
 
-	if rv == nil {
-		return context.WithValue(ctx, requestKey, Context(&map[string]interface{}{key: value}))
+	if s == nil {
+		return context.WithValue(ctx, requestKey, newStore(map[string]interface{}{key: value}))
 	}
 
-	(*rv.(Context))[key] = value
+	s.lock.Lock()
+	(*s.data)[key] = value
+	s.lock.Unlock()
 	return ctx
 }
 
 // NewContext 新建并初始化一个上下文
 func NewContext(ctx context.Context) context.Context {
-	return context.WithValue(ctx, requestKey, Context(&map[string]interface{}{}))
+	return context.WithValue(ctx, requestKey, newStore(map[string]interface{}{}))
 }
 
 // CopyContext 在原有上下文基础上拷贝一份
 func CopyContext(ctx context.Context) context.Context {
-	rv := ctx.Value(requestKey)
+	s := storeOf(ctx)
 
-	if rv == nil {
+	if s == nil {
 		return NewContext(ctx)
 	}
 
 	newContextContent := map[string]interface{}{}
 
-	for key, value := range *rv.(Context) {
+	s.lock.RLock()
+	for key, value := range *s.data {
 		newContextContent[key] = value
 	}
+	s.lock.RUnlock()
 
-	return context.WithValue(ctx, requestKey, Context(&newContextContent))
+	return context.WithValue(ctx, requestKey, newStore(newContextContent))
 }
 
 // GetContextValueString 获取上下文信息
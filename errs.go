@@ -20,6 +20,7 @@ const (
 	ErrRequestValidate = errs.ErrRequestValidate
 	ErrDefault         = errs.ErrDefault
 	ErrModelSelect     = errs.ErrModelSelect
+	ErrBodyTooLarge    = errs.ErrBodyTooLarge
 )
 
 var (
@@ -29,4 +30,5 @@ var (
 	GlobalValidateErrorHandler         = errs.GlobalValidateErrorHandler
 	RegisterGlobalValidateErrorHandler = errs.RegisterGlobalValidateErrorHandler
 	RegisterValidateErrorFunc          = errs.RegisterValidateErrorFunc
+	ValidateFieldErrors                = errs.FieldErrors
 )
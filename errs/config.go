@@ -0,0 +1,103 @@
+package errs
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type configKey struct{}
+
+// Config errs 的实例级配置，通过 WithConfig 注入请求上下文后当前请求将使用该配置，未注入时使用 DefaultConfig
+type Config struct {
+	lock          sync.RWMutex
+	hub           map[ValidateErrorType]ValidateErrorFunc
+	globalHandler ValidateErrorFunc
+}
+
+// DefaultConfig 默认配置，解析错误处理器初始为 ValidateErrorHub 的拷贝，之后只能通过加锁的方法访问
+var DefaultConfig = newConfig(ValidateErrorHub)
+
+// NewConfig 获取一个新的配置实例，解析错误处理器初始为 DefaultConfig 中已注册处理器的拷贝
+func NewConfig() *Config {
+	DefaultConfig.lock.RLock()
+	defer DefaultConfig.lock.RUnlock()
+
+	return newConfig(DefaultConfig.hub)
+}
+
+func newConfig(hub map[ValidateErrorType]ValidateErrorFunc) *Config {
+	c := &Config{hub: make(map[ValidateErrorType]ValidateErrorFunc, len(hub))}
+	for errType, fun := range hub {
+		c.hub[errType] = fun
+	}
+
+	return c
+}
+
+// RegisterGlobalValidateErrorHandler 注册全局 ValidateError 异常处理器，注册后，指定类型的 ValidateError handler 将不在执行
+func (c *Config) RegisterGlobalValidateErrorHandler(fun ValidateErrorFunc) {
+	c.lock.Lock()
+	c.globalHandler = fun
+	c.lock.Unlock()
+}
+
+// GlobalValidateErrorHandler 获取全局 ValidateError 异常处理器
+func (c *Config) GlobalValidateErrorHandler() ValidateErrorFunc {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.globalHandler
+}
+
+// RegisterValidateErrorFunc 注册一个解析失败的处理器
+func (c *Config) RegisterValidateErrorFunc(errType ValidateErrorType, fun ValidateErrorFunc) {
+	c.lock.Lock()
+	c.hub[errType] = fun
+	c.lock.Unlock()
+}
+
+// ValidateErrorHandler 获取指定类型的解析失败处理器
+func (c *Config) ValidateErrorHandler(errType ValidateErrorType) (ValidateErrorFunc, bool) {
+	c.lock.RLock()
+	fun, exists := c.hub[errType]
+	c.lock.RUnlock()
+	return fun, exists
+}
+
+// WithConfig 将配置注入至 ctx
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf 获取当前请求使用的配置，未注入时返回 DefaultConfig
+func ConfigOf(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+
+	return DefaultConfig
+}
+
+type routeValidateErrorHubKey struct{}
+
+// WithValidateErrorHub 将路由级的解析失败处理器注入至 ctx，其优先级高于 Config 中注册的处理器及全局 ValidateError 异常处理器
+func WithValidateErrorHub(ctx context.Context, hub map[ValidateErrorType]ValidateErrorFunc) context.Context {
+	return context.WithValue(ctx, routeValidateErrorHubKey{}, hub)
+}
+
+// LookupValidateErrorHandler 获取当前请求指定类型的解析失败处理器，
+// 依次查找路由级处理器、当前请求配置的全局 ValidateError 异常处理器及当前请求配置中注册的处理器
+func LookupValidateErrorHandler(r *http.Request, errType ValidateErrorType) (ValidateErrorFunc, bool) {
+	if hub, ok := r.Context().Value(routeValidateErrorHubKey{}).(map[ValidateErrorType]ValidateErrorFunc); ok {
+		if fun, exists := hub[errType]; exists {
+			return fun, true
+		}
+	}
+
+	config := ConfigOf(r)
+	if globalHandler := config.GlobalValidateErrorHandler(); globalHandler != nil {
+		return globalHandler, true
+	}
+
+	return config.ValidateErrorHandler(errType)
+}
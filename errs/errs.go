@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tangzixiang/mplus/message"
 	"github.com/tangzixiang/mplus/mhttp"
+	"gopkg.in/go-playground/validator.v9"
 )
 
 // ValidateErrorType 请求数据读取及校验异常类型
@@ -16,7 +17,8 @@ type ValidateErrorType uint8
 const (
 	// ErrBodyRead 请求体读取失败
 	// 出现于 mplus.Bind()，
-	// 若当前请求为 POST/PUT/PATCH/DELETE 请求且格式为 json 时，读取 r.body 失败时触发
+	// 若当前请求为 POST/PUT/PATCH/DELETE 请求且格式为 json 时，读取 r.body 失败时触发，
+	// 请求体按 Content-Encoding 解压失败时同样触发
 	// 默认不强校验 body 数据为空的情况，可以通过 mplus.SetStrictJSONBodyCheck 更改为强校验模式
 	ErrBodyRead ValidateErrorType = iota
 	// ErrBodyUnmarshal 请求体序列化失败
@@ -32,7 +34,8 @@ const (
 	ErrMediaTypeParse
 	// ErrMediaType 不支持的媒体类型
 	// 出现于 mplus.Bind()，
-	// 若当前请求为 POST/PUT/PATCH/DELETE 请求且格式不为  x-www-form-urlencoded/form-data/json 时触发
+	// 若当前请求为 POST/PUT/PATCH/DELETE 请求且格式不为  x-www-form-urlencoded/form-data/json 时触发，
+	// 请求体的 Content-Encoding 不为 gzip/deflate/identity 时同样触发
 	ErrMediaType
 	// ErrDecode 请求参数解析失败
 	// 出现于 mplus.Bind()，
@@ -63,6 +66,10 @@ const (
 	// 出现于 mplus.Bind() ，
 	// 当传递的参数为函数类型，且函数执行返回异常时触发，函数型参数仅在请求进入路由链路时执行
 	ErrModelSelectType
+	// ErrBodyTooLarge 请求体解压后超出限制
+	// 出现于 mplus.Bind()，
+	// 若当前请求为 POST/PUT/PATCH/DELETE 请求且携带 Content-Encoding 时，请求体解压后的大小超出 mplus.MaxDecompressSize 时触发
+	ErrBodyTooLarge
 )
 
 // ValidateErrorTypeMsg ValidateErrorType 异常与描述信息
@@ -79,6 +86,7 @@ var ValidateErrorTypeMsg = map[ValidateErrorType]string{
 	ErrDefault:         "validate request failed",
 	ErrModelSelect:     "select request model failed",
 	ErrModelSelectType: "select request model type error,must be ptr",
+	ErrBodyTooLarge:    "request body too large",
 }
 
 // ValidateError 校验异常
@@ -120,52 +128,84 @@ func ValidateErrorWrap(err error, errType ValidateErrorType) error {
 	return errors.Wrap(ValidateError{errType: errType, lastErr: err}, ValidateErrorTypeMsg[errType])
 }
 
+// FieldErrors 提取 ErrBodyValidate 异常中各字段的校验异常，非 validator 校验异常时返回 nil
+func FieldErrors(err error) []mhttp.FieldError {
+	cErr, ok := errors.Cause(err).(ValidateError)
+	if !ok {
+		return nil
+	}
+
+	vErrs, ok := cErr.LastErr().(validator.ValidationErrors)
+	if !ok {
+		return nil
+	}
+
+	fieldErrors := make([]mhttp.FieldError, 0, len(vErrs))
+	for _, vErr := range vErrs {
+		fieldErrors = append(fieldErrors, mhttp.FieldError{
+			Field:   vErr.Namespace(),
+			Rule:    vErr.Tag(),
+			Message: vErr.Namespace() + " failed on tag '" + vErr.Tag() + "'",
+		})
+	}
+
+	return fieldErrors
+}
+
 // ValidateErrorFunc 请求解析失败的处理器
 type ValidateErrorFunc func(w http.ResponseWriter, r *http.Request, err error)
 
-// ValidateErrorHub 解析错误处理器
+// ValidateErrorHub 内置的解析错误处理器，仅作为 DefaultConfig 的初始值，初始化后修改不会影响任何配置
+//
+// Deprecated: 通过 RegisterValidateErrorFunc 或 Config.RegisterValidateErrorFunc 注册处理器
 var ValidateErrorHub = map[ValidateErrorType]ValidateErrorFunc{
 	ErrBodyRead: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrBodyUnmarshal: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrMediaType: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusUnsupportedMediaType.Set(err.Error()), http.StatusUnsupportedMediaType)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusUnsupportedMediaType.Copy().Set(err.Error()), http.StatusUnsupportedMediaType)
 	},
 	ErrMediaTypeParse: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusUnsupportedMediaType.Set(err.Error()), http.StatusUnsupportedMediaType)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusUnsupportedMediaType.Copy().Set(err.Error()), http.StatusUnsupportedMediaType)
+	},
+	ErrBodyTooLarge: func(w http.ResponseWriter, r *http.Request, err error) {
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusRequestEntityTooLarge.Copy().Set(err.Error()), http.StatusRequestEntityTooLarge)
 	},
 	ErrBodyParse: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrDecode: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrParseQuery: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrBodyValidate: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.SetFieldErrors(r, FieldErrors(err))
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrRequestValidate: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 	ErrDefault: func(w http.ResponseWriter, r *http.Request, err error) {
-		mhttp.CallRegisterFuncOrAbortError(w, r, message.MessageStatusBadRequest.Set(err.Error()), http.StatusBadRequest)
+		mhttp.CallRegisterFuncOrAbortEnvelope(w, r, message.MessageStatusBadRequest.Copy().Set(err.Error()), http.StatusBadRequest)
 	},
 }
 
-// RegisterGlobalValidateErrorHandler 全局解析异常处理器
+// GlobalValidateErrorHandler 仅为兼容保留，不会被读取也不会随注册更新
+//
+// Deprecated: 通过 RegisterGlobalValidateErrorHandler 注册，通过 Config.GlobalValidateErrorHandler 获取
 var GlobalValidateErrorHandler ValidateErrorFunc
 
 // RegisterGlobalValidateErrorHandler 注册全局 ValidateError 异常处理器，注册后，指定类型的 ValidateError handler 将不在执行
 func RegisterGlobalValidateErrorHandler(fun ValidateErrorFunc) {
-	GlobalValidateErrorHandler = fun
+	DefaultConfig.RegisterGlobalValidateErrorHandler(fun)
 }
 
 // RegisterValidateErrorFunc 注册一个解析失败的处理器
 func RegisterValidateErrorFunc(errType ValidateErrorType, fun ValidateErrorFunc) {
-	ValidateErrorHub[errType] = fun
+	DefaultConfig.RegisterValidateErrorFunc(errType, fun)
 }
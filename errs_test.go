@@ -1,6 +1,7 @@
 package mplus
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -59,3 +60,51 @@ func TestValidateErrorWrap(t *testing.T) {
 	assert.Equal(t, "TestValidateErrorWrap", errors.Cause(wrapErr).(ValidateError).Error())
 	assert.Equal(t, err, errors.Cause(wrapErr).(ValidateError).LastErr())
 }
+
+func TestValidateErrorHubEnvelope(t *testing.T) {
+	BeforeTest(true)
+	defer AfterTest(true)
+
+	SetEnvelope(DefaultEnvelope)
+	defer SetEnvelope(nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1", strings.NewReader("{")) // invalid json
+	r = SetRequestHeader(r, HeaderContentType, MIMEJSON)
+	r = r.WithContext(NewContext(r.Context()))
+
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	Bind((*body)(nil)).ServeHTTP(NewResponseWrite(w), r)
+
+	assert.True(t, IsAbort(r))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	resp := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(MessageStatusBadRequest.ErrCode()), resp[EnvelopeCodeKey])
+	assert.Contains(t, resp, EnvelopeMessageKey)
+	assert.Equal(t, map[string]interface{}{}, resp[EnvelopeDataKey])
+}
+
+func TestValidateErrorHubNotMutateMessage(t *testing.T) {
+	BeforeTest(true)
+	defer AfterTest(true)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1", strings.NewReader("{")) // invalid json
+	r = SetRequestHeader(r, HeaderContentType, MIMEJSON)
+	r = r.WithContext(NewContext(r.Context()))
+
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	Bind((*body)(nil)).ServeHTTP(NewResponseWrite(w), r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEqual(t, http.StatusText(http.StatusBadRequest)+"\n", w.Body.String())
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), MessageStatusBadRequest.Default())
+}
@@ -3,6 +3,7 @@ module github.com/tangzixiang/mplus
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/go-playground/form v3.1.4+incompatible
 	github.com/go-playground/locales v0.12.1 // indirect
 	github.com/go-playground/universal-translator v0.16.0 // indirect
@@ -15,4 +16,5 @@ require (
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 	gopkg.in/go-playground/validator.v9 v9.29.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -4,6 +4,8 @@ import (
 	"github.com/tangzixiang/mplus/header"
 )
 
+type ForwardedElement = header.ForwardedElement
+
 // 请求头常量
 const (
 	HeaderAccept                          = header.Accept
@@ -111,6 +113,10 @@ const (
 	HeaderForwardedFor                    = header.ForwardedFor
 	HeaderRealIP                          = header.RealIP
 	HeaderAppEngineRemoteAddr             = header.AppEngineRemoteAddr
+	HeaderAPIKey                          = header.APIKey
+	HeaderRateLimitLimit                  = header.RateLimitLimit
+	HeaderRateLimitRemaining              = header.RateLimitRemaining
+	HeaderRateLimitReset                  = header.RateLimitReset
 )
 
 const (
@@ -119,6 +125,8 @@ const (
 	ContentTypeText              = header.ContentTypeText
 	ContentTypeXML               = header.ContentTypeXML
 	ContentTypeStream            = header.ContentTypeStream
+	ContentTypeProblemJSON       = header.ContentTypeProblemJSON
+	ContentTypeMarkdown          = header.ContentTypeMarkdown
 	ContentTypeHTML              = header.ContentTypeHTML
 	ContentTypeXML2              = header.ContentTypeXML2
 	ContentTypePlain             = header.ContentTypePlain
@@ -128,6 +136,12 @@ const (
 	ContentTypeMSGPACK2          = header.ContentTypeMSGPACK2
 )
 
+// Content-Disposition 类型
+const (
+	DispositionInline     = header.DispositionInline
+	DispositionAttachment = header.DispositionAttachment
+)
+
 // 请求头分割字符
 const (
 	SplitSepBlankSpace = header.SplitSepBlankSpace
@@ -161,4 +175,12 @@ var (
 	SetRequestHeaderRequestID  = header.SetRequestHeaderRequestID
 	SetResponseHeaderRequestID = header.SetResponseHeaderRequestID
 	GetClientIP                = header.GetClientIP
+	GetScheme                  = header.GetScheme
+	GetHost                    = header.GetHost
+	SetTrustedProxies          = header.SetTrustedProxies
+	TrustedProxies             = header.TrustedProxies
+	IsTrustedProxy             = header.IsTrustedProxy
+	ParseCIDR                  = header.ParseCIDR
+	ParseForwarded             = header.ParseForwarded
+	FormatContentDisposition   = header.FormatContentDisposition
 )
@@ -107,6 +107,10 @@ const (
 	ForwardedFor                    = "X-Forwarded-For"
 	RealIP                          = "X-Real-Ip"
 	AppEngineRemoteAddr             = "X-Appengine-Remote-Addr"
+	APIKey                          = "X-Api-Key"
+	RateLimitLimit                  = "RateLimit-Limit"
+	RateLimitRemaining              = "RateLimit-Remaining"
+	RateLimitReset                  = "RateLimit-Reset"
 )
 
 const (
@@ -122,6 +126,8 @@ const (
 	ContentTypePROTOBUF          = "application/x-protobuf"
 	ContentTypeMSGPACK           = "application/x-msgpack"
 	ContentTypeMSGPACK2          = "application/msgpack"
+	ContentTypeProblemJSON       = "application/problem+json"
+	ContentTypeMarkdown          = "text/markdown"
 )
 
 // 请求头分割字符
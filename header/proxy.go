@@ -0,0 +1,345 @@
+package header
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type configKey struct{}
+
+// Config header 的实例级配置，通过 WithConfig 注入请求上下文后当前请求将使用该配置，未注入时使用 DefaultConfig
+type Config struct {
+	lock           sync.RWMutex
+	trustedProxies []*net.IPNet
+}
+
+// DefaultConfig 默认配置，包级别的 SetTrustedProxies 等函数均作用于该配置
+var DefaultConfig = NewConfig()
+
+// NewConfig 获取一个新的配置实例
+func NewConfig() *Config {
+	return new(Config)
+}
+
+// SetTrustedProxies 设置受信任的代理，see SetTrustedProxies
+func (c *Config) SetTrustedProxies(proxies ...string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		ipNet, err := ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipNet)
+	}
+
+	c.lock.Lock()
+	c.trustedProxies = nets
+	c.lock.Unlock()
+	return nil
+}
+
+// TrustedProxies 获取受信任的代理
+func (c *Config) TrustedProxies() []*net.IPNet {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.trustedProxies
+}
+
+// IsTrustedProxy 判断 ip 是否为受信任的代理
+func (c *Config) IsTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range c.TrustedProxies() {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithConfig 将配置注入至 ctx
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf 获取当前请求使用的配置，未注入时返回 DefaultConfig
+func ConfigOf(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+
+	return DefaultConfig
+}
+
+// SetTrustedProxies 设置 DefaultConfig 受信任的代理，支持 CIDR 及单个 IP（v4/v6），如 10.0.0.0/8、::1
+//
+// 未设置受信任的代理时，GetClientIP、GetScheme 及 GetHost 忽略全部代理请求头，只使用直连地址；
+// 设置后仅在直连地址为受信任的代理时才解析代理请求头，并自右向左跳过受信任的代理，第一个不受信任的地址即为客户端 IP
+func SetTrustedProxies(proxies ...string) error {
+	return DefaultConfig.SetTrustedProxies(proxies...)
+}
+
+// TrustedProxies 获取 DefaultConfig 受信任的代理
+func TrustedProxies() []*net.IPNet {
+	return DefaultConfig.TrustedProxies()
+}
+
+// ParseCIDR 解析 CIDR，单个 IP 解析为仅包含该 IP 的网段
+func ParseCIDR(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
+
+// IsTrustedProxy 判断 ip 是否为 DefaultConfig 受信任的代理
+func IsTrustedProxy(ip net.IP) bool {
+	return DefaultConfig.IsTrustedProxy(ip)
+}
+
+// ForwardedElement RFC 7239 Forwarded 请求头中的一个节点
+type ForwardedElement struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+// ParseForwarded 解析 RFC 7239 Forwarded 请求头，多个请求头按出现顺序合并
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func ParseForwarded(values ...string) []ForwardedElement {
+	var elements []ForwardedElement
+
+	for _, value := range values {
+		for _, part := range splitQuoted(value, ',') {
+			var element ForwardedElement
+
+			for _, pair := range splitQuoted(part, ';') {
+				i := strings.IndexByte(pair, '=')
+				if i < 0 {
+					continue
+				}
+
+				key := strings.ToLower(strings.TrimSpace(pair[:i]))
+				v := strings.TrimSpace(pair[i+1:])
+				if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+					v = strings.Replace(v[1:len(v)-1], `\"`, `"`, -1)
+				}
+
+				switch key {
+				case "for":
+					element.For = v
+				case "by":
+					element.By = v
+				case "host":
+					element.Host = v
+				case "proto":
+					element.Proto = strings.ToLower(v)
+				}
+			}
+
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
+
+// splitQuoted 以 sep 分割 s，忽略引号内的 sep
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+
+	return parts
+}
+
+// parseNodeIP 解析 Forwarded for 节点或 X-Forwarded-For 中的地址，支持携带端口及 IPv6 方括号格式，无法解析时返回 nil
+func parseNodeIP(node string) net.IP {
+	node = strings.TrimSpace(node)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+
+	return net.ParseIP(strings.Trim(node, "[]"))
+}
+
+// remoteIP 获取请求的直连地址
+func remoteIP(r *http.Request) net.IP {
+	return parseNodeIP(r.RemoteAddr)
+}
+
+// forwardedHop 经代理转发的请求中客户端一侧的节点信息
+type forwardedHop struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+// resolveForwarded 解析请求的客户端节点，受信任的代理取自当前请求的配置，仅在直连地址为受信任的代理时解析代理请求头，
+// 优先使用 Forwarded，其次为 X-Forwarded-For、X-Forwarded-Proto、X-Forwarded-Host 及 X-Real-Ip，
+// 各请求头均自右向左跳过受信任的代理，第一个不受信任的节点即为客户端
+func resolveForwarded(r *http.Request) forwardedHop {
+	config := ConfigOf(r)
+
+	remote := remoteIP(r)
+	if remote == nil || !config.IsTrustedProxy(remote) {
+		return forwardedHop{ip: remote}
+	}
+
+	client := forwardedHop{ip: remote}
+
+	// Forwarded 中每个代理追加的节点描述其收到的请求，客户端节点的 proto 及 host 即为客户端请求使用的协议及主机名
+	if forwarded := GetHeaderValues(r, Forwarded); len(forwarded) != 0 {
+		elements := ParseForwarded(forwarded...)
+		for i := len(elements) - 1; i >= 0; i-- {
+			ip := parseNodeIP(elements[i].For)
+			if ip == nil { // 无法解析的节点视为链路中断，使用最后一个受信任的节点
+				break
+			}
+
+			client.ip = ip
+			if elements[i].Proto != "" {
+				client.proto = elements[i].Proto
+			}
+			if elements[i].Host != "" {
+				client.host = elements[i].Host
+			}
+
+			if !config.IsTrustedProxy(ip) {
+				break
+			}
+		}
+
+		return client
+	}
+
+	hops := splitHeaderValues(r, ForwardedFor)
+	protos := splitHeaderValues(r, ForwardedProto)
+	hosts := splitHeaderValues(r, ForwardedHost)
+
+	if len(hops) == 0 {
+		if ip := parseNodeIP(GetHeader(r, RealIP)); ip != nil {
+			client.ip = ip
+		} else if ip := parseNodeIP(GetHeader(r, AppEngineRemoteAddr)); ip != nil {
+			client.ip = ip
+		}
+		client.proto = alignedHop(protos, 0, 0)
+		client.host = alignedHop(hosts, 0, 0)
+		return client
+	}
+
+	// X-Forwarded-Proto、X-Forwarded-Host 与 X-Forwarded-For 自右向左对齐，
+	// 较短时使用最左侧的值，该值由客户端节点右侧受信任的代理设置
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseNodeIP(hops[i])
+		if ip == nil {
+			break
+		}
+
+		client.ip = ip
+		if proto := alignedHop(protos, len(hops), i); proto != "" {
+			client.proto = proto
+		}
+		if host := alignedHop(hosts, len(hops), i); host != "" {
+			client.host = host
+		}
+
+		if !config.IsTrustedProxy(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+// splitHeaderValues 获取以逗号分隔的请求头的全部值，多个请求头按出现顺序合并
+func splitHeaderValues(r *http.Request, key string) []string {
+	var values []string
+	for _, value := range GetHeaderValues(r, key) {
+		for _, v := range strings.Split(value, SplitSepComma) {
+			values = append(values, strings.TrimSpace(v))
+		}
+	}
+
+	return values
+}
+
+// alignedHop 获取与 X-Forwarded-For 中第 i 个节点（共 n 个）自右向左对齐的值，n 为 0 时获取最右侧的值
+func alignedHop(values []string, n, i int) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	j := len(values) - (n - i)
+	if n == 0 {
+		j = len(values) - 1
+	}
+	if j < 0 {
+		j = 0
+	}
+
+	return values[j]
+}
+
+// GetScheme 获取客户端请求使用的协议，http 或 https
+//
+// 仅在直连地址为受信任的代理时使用 Forwarded 中的 proto 或 X-Forwarded-Proto，并与客户端 IP 一样自右向左跳过受信任的代理
+func GetScheme(r *http.Request) string {
+	if proto := resolveForwarded(r).proto; proto != "" {
+		return strings.ToLower(proto)
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// GetHost 获取客户端请求的主机名
+//
+// 仅在直连地址为受信任的代理时使用 Forwarded 中的 host 或 X-Forwarded-Host，并与客户端 IP 一样自右向左跳过受信任的代理
+func GetHost(r *http.Request) string {
+	if host := resolveForwarded(r).host; host != "" {
+		return host
+	}
+
+	return r.Host
+}
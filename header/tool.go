@@ -1,9 +1,9 @@
 package header
 
 import (
-	"net"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 )
 
 // GetHeader 获取指定请求头
@@ -185,30 +185,71 @@ func SetResponseHeaderRequestID(w http.ResponseWriter, id string) http.ResponseW
 }
 
 // GetClientIP 获取客户端 IP 地址
-// ClientIP implements a best effort algorithm to return the real client IP, it parses
-// X-Real-IP and X-Forwarded-For in order to work properly with reverse-proxies such us: nginx or haproxy.
-// Use X-Forwarded-For before X-Real-Ip as nginx uses X-Real-Ip with the proxy's IP.
+//
+// 默认只使用请求的直连地址，忽略 X-Forwarded-For、X-Real-Ip 等可被客户端伪造的请求头；
+// 通过 SetTrustedProxies 或当前请求配置的 Config.SetTrustedProxies 设置受信任的代理后，仅在直连地址为受信任的代理时才依次解析 Forwarded、X-Forwarded-For、X-Real-Ip 及 X-Appengine-Remote-Addr，
+// 并自右向左跳过受信任的代理，详见 SetTrustedProxies
 func GetClientIP(r *http.Request) string {
-	clientIP := GetHeader(r, ForwardedFor)
-	clientIP = strings.TrimSpace(strings.Split(clientIP, ",")[0])
+	if ip := resolveForwarded(r).ip; ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
+
+// Content-Disposition 类型
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
 
-	if clientIP == "" {
-		clientIP = strings.TrimSpace(GetHeader(r, RealIP))
+// FormatContentDisposition 构造 Content-Disposition 响应头内容，filename 同时以 ASCII 兼容格式及 RFC 5987 编码格式输出
+//
+//	FormatContentDisposition("attachment", "报表.csv") => attachment; filename="__.csv"; filename*=UTF-8''%E6%8A%A5%E8%A1%A8.csv
+func FormatContentDisposition(dispositionType, filename string) string {
+	if filename == "" {
+		return dispositionType
+	}
+
+	fallback := make([]byte, 0, len(filename))
+	encoded := make([]byte, 0, len(filename))
+	isASCII := true
+
+	for _, r := range filename {
+		switch {
+		case r >= utf8.RuneSelf || r < 0x20 || r == 0x7f:
+			isASCII = false
+			fallback = append(fallback, '_')
+		case r == '"' || r == '\\':
+			fallback = append(fallback, '\\', byte(r))
+		default:
+			fallback = append(fallback, byte(r))
+		}
 	}
 
-	if clientIP != "" {
-		return clientIP
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded = append(encoded, b)
+		} else {
+			encoded = append(encoded, '%', upperHex[b>>4], upperHex[b&0x0f])
+		}
 	}
 
-	// #726 #755 If enabled, it will thrust some headers starting with
-	// 'X-AppEngine...' for better integration with that PaaS.
-	if addr := GetHeader(r, AppEngineRemoteAddr); addr != "" {
-		return addr
+	value := dispositionType + `; filename="` + string(fallback) + `"`
+	if !isASCII {
+		value += `; filename*=UTF-8''` + string(encoded)
 	}
 
-	if ip, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr)); err == nil {
-		return ip
+	return value
+}
+
+const upperHex = "0123456789ABCDEF"
+
+// isAttrChar 判断是否为 RFC 5987 attr-char，其余字符需要进行百分号编码
+func isAttrChar(b byte) bool {
+	if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' {
+		return true
 	}
 
-	return ""
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
 }
@@ -489,6 +489,7 @@ func TestGetClientIP(t *testing.T) {
 		HeaderAppEngineRemoteAddr: localPath,
 	}
 
+	// 未设置受信任的代理时忽略代理请求头，只使用直连地址
 	for key, value := range headers {
 
 		r := SetRequestHeader( // set header and get back req
@@ -496,6 +497,104 @@ func TestGetClientIP(t *testing.T) {
 			key, value,
 		)
 
-		assert.Equal(t, value, GetClientIP(r))
+		assert.Equal(t, "192.0.2.1", GetClientIP(r), key)
 	}
+
+	// 直连地址为受信任的代理时解析代理请求头
+	assert.Nil(t, SetTrustedProxies("192.0.2.1"))
+	defer func() { _ = SetTrustedProxies() }()
+
+	for key, want := range map[string]string{
+		HeaderForwardedFor:        "127.0.0.1",
+		HeaderRealIP:              "127.0.0.1",
+		HeaderAppEngineRemoteAddr: "127.0.0.1",
+	} {
+		r := SetRequestHeader(httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil), key, localPath)
+		assert.Equal(t, want, GetClientIP(r), key)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	assert.NotNil(t, SetTrustedProxies("10.0.0.0/8", "bad"))
+	assert.Nil(t, SetTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8"))
+	defer func() { _ = SetTrustedProxies() }()
+
+	newRequest := func(remoteAddr string, headers map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://api.example.com/orders", nil)
+		r.RemoteAddr = remoteAddr
+		return SetRequestHeaders(r, headers)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"untrusted remote", "203.0.113.9:1234", map[string]string{HeaderForwardedFor: "1.1.1.1"}, "203.0.113.9"},
+		{"skip trusted hops", "10.0.0.2:1234", map[string]string{HeaderForwardedFor: "6.6.6.6, 1.1.1.1, 192.168.1.1, 10.0.0.3"}, "1.1.1.1"},
+		{"all trusted", "10.0.0.2:1234", map[string]string{HeaderForwardedFor: "10.0.0.5, 10.0.0.3"}, "10.0.0.5"},
+		{"real ip", "10.0.0.2:1234", map[string]string{HeaderRealIP: "2.2.2.2"}, "2.2.2.2"},
+		{"app engine", "10.0.0.2:1234", map[string]string{HeaderAppEngineRemoteAddr: "3.3.3.3"}, "3.3.3.3"},
+		{"untrusted app engine", "203.0.113.9:1234", map[string]string{HeaderAppEngineRemoteAddr: "3.3.3.3"}, "203.0.113.9"},
+		{"no header", "10.0.0.2:1234", nil, "10.0.0.2"},
+		{"forwarded", "[fd00::1]:443", map[string]string{
+			HeaderForwarded:    `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`,
+			HeaderForwardedFor: "7.7.7.7",
+		}, "2001:db8:cafe::17"},
+		{"unknown hop", "10.0.0.2:1234", map[string]string{HeaderForwarded: "for=unknown, for=10.0.0.3"}, "10.0.0.3"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, GetClientIP(newRequest(tt.remoteAddr, tt.headers)), tt.name)
+	}
+
+	// 协议及主机名
+	r := newRequest("10.0.0.2:1234", map[string]string{HeaderForwarded: `for=1.1.1.1;proto=HTTPS;host="shop.example.com"`})
+	assert.Equal(t, "https", GetScheme(r))
+	assert.Equal(t, "shop.example.com", GetHost(r))
+
+	r = newRequest("10.0.0.2:1234", map[string]string{HeaderForwardedProto: "https", HeaderForwardedHost: "www.example.com"})
+	pp := PlusPlus(httptest.NewRecorder(), r)
+	assert.Equal(t, "https", pp.GetScheme())
+	assert.Equal(t, "www.example.com", pp.GetHost())
+
+	r = newRequest("203.0.113.9:1234", map[string]string{HeaderForwardedProto: "https", HeaderForwardedHost: "www.example.com"})
+	assert.Equal(t, "http", GetScheme(r))
+	assert.Equal(t, "api.example.com", GetHost(r))
+
+	// 自右向左跳过受信任的代理，客户端伪造的最左侧节点不被采用
+	r = newRequest("10.0.0.2:1234", map[string]string{
+		HeaderForwarded: `for=6.6.6.6;proto=http;host=evil.example.com, for=1.1.1.1;proto=https;host=shop.example.com, for=10.0.0.3;proto=http;host=internal`,
+	})
+	assert.Equal(t, "1.1.1.1", GetClientIP(r))
+	assert.Equal(t, "https", GetScheme(r))
+	assert.Equal(t, "shop.example.com", GetHost(r))
+
+	r = newRequest("10.0.0.2:1234", map[string]string{
+		HeaderForwardedFor:   "6.6.6.6, 1.1.1.1, 10.0.0.3",
+		HeaderForwardedProto: "http, https, http",
+		HeaderForwardedHost:  "evil.example.com, shop.example.com, internal",
+	})
+	assert.Equal(t, "1.1.1.1", GetClientIP(r))
+	assert.Equal(t, "https", GetScheme(r))
+	assert.Equal(t, "shop.example.com", GetHost(r))
+
+	// 只有受信任的代理设置的单个值时使用该值
+	r = newRequest("10.0.0.2:1234", map[string]string{HeaderForwardedFor: "6.6.6.6, 1.1.1.1", HeaderForwardedProto: "https"})
+	assert.Equal(t, "https", GetScheme(r))
+
+	// 未设置受信任的代理时忽略代理请求头
+	assert.Nil(t, SetTrustedProxies())
+	r = newRequest("10.0.0.2:1234", map[string]string{
+		HeaderForwardedFor:   "1.1.1.1",
+		HeaderForwardedProto: "https",
+		HeaderForwardedHost:  "www.example.com",
+	})
+	assert.Equal(t, "10.0.0.2", GetClientIP(r))
+	assert.Equal(t, "http", GetScheme(r))
+	assert.Equal(t, "api.example.com", GetHost(r))
+
+	elements := ParseForwarded(`for="_gazonk";by=203.0.113.43`, `for=192.0.2.43`)
+	assert.Equal(t, []ForwardedElement{{For: "_gazonk", By: "203.0.113.43"}, {For: "192.0.2.43"}}, elements)
 }
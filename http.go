@@ -6,14 +6,65 @@ import (
 
 type StatusMethodCallback = mhttp.StatusMethodCallback
 type ResponseWriter = mhttp.ResponseWriter
+type EnvelopeFunc = mhttp.EnvelopeFunc
+type Problem = mhttp.Problem
+type ProblemTypeFunc = mhttp.ProblemTypeFunc
+type FieldError = mhttp.FieldError
+type StatusClass = mhttp.StatusClass
+type StatusMethodHub = mhttp.StatusMethodHub
+type Principal = mhttp.Principal
+
+// 状态码类别
+const (
+	StatusClassInformational = mhttp.StatusClassInformational
+	StatusClassSuccess       = mhttp.StatusClassSuccess
+	StatusClassRedirection   = mhttp.StatusClassRedirection
+	StatusClassClientError   = mhttp.StatusClassClientError
+	StatusClassServerError   = mhttp.StatusClassServerError
+)
+
+// 默认的响应信封字段
+const (
+	EnvelopeCodeKey    = mhttp.EnvelopeCodeKey
+	EnvelopeMessageKey = mhttp.EnvelopeMessageKey
+	EnvelopeDataKey    = mhttp.EnvelopeDataKey
+)
+
+// 错误码导出格式
+const (
+	ExportFormatJSON     = mhttp.ExportFormatJSON
+	ExportFormatMarkdown = mhttp.ExportFormatMarkdown
+	ExportFormatQueryKey = mhttp.ExportFormatQueryKey
+)
+
+// 问题详情的默认类型及扩展字段
+const (
+	ProblemTypeBlank      = mhttp.ProblemTypeBlank
+	ProblemCodeKey        = mhttp.ProblemCodeKey
+	ProblemRequestIDKey   = mhttp.ProblemRequestIDKey
+	ProblemFieldErrorsKey = mhttp.ProblemFieldErrorsKey
+)
 
 var (
 	EmptyRespData                     = mhttp.EmptyRespData
 	DefaultMemorySize                 = mhttp.DefaultMemorySize
 	SetDefaultMemorySize              = mhttp.SetDefaultMemorySize
+	MaxDecompressSize                 = mhttp.MaxDecompressSize
+	SetMaxDecompressSize              = mhttp.SetMaxDecompressSize
+	DecodeRequestBody                 = mhttp.DecodeRequestBody
+	ErrContentEncoding                = mhttp.ErrContentEncoding
 	Abort                             = mhttp.Abort
 	NotAbort                          = mhttp.NotAbort
 	IsAbort                           = mhttp.IsAbort
+	AbortWithReason                   = mhttp.AbortWithReason
+	SetAbortReason                    = mhttp.SetAbortReason
+	AbortReason                       = mhttp.AbortReason
+	WithRoutePattern                  = mhttp.WithRoutePattern
+	RoutePattern                      = mhttp.RoutePattern
+	SetPrincipal                      = mhttp.SetPrincipal
+	GetPrincipal                      = mhttp.GetPrincipal
+	WithRouteRequirements             = mhttp.WithRouteRequirements
+	RouteRequirements                 = mhttp.RouteRequirements
 	Error                             = mhttp.Error
 	ErrorEmpty                        = mhttp.ErrorEmpty
 	Plain                             = mhttp.Plain
@@ -27,11 +78,23 @@ var (
 	JSONOK                            = mhttp.JSONOK
 	DumpRequest                       = mhttp.DumpRequest
 	DumpRequestPure                   = mhttp.DumpRequestPure
+	ReadRequestBody                   = mhttp.ReadRequestBody
 	RegisterHttpStatusMethod          = mhttp.RegisterHttpStatusMethod
+	RegisterHttpStatusClassMethod     = mhttp.RegisterHttpStatusClassMethod
+	RegisterHttpStatusFallbackMethod  = mhttp.RegisterHttpStatusFallbackMethod
+	StatusClassOf                     = mhttp.StatusClassOf
+	NewStatusMethodHub                = mhttp.NewStatusMethodHub
+	LookupStatusMethod                = mhttp.LookupStatusMethod
+	CallRegisterFuncOrError           = mhttp.CallRegisterFuncOrError
+	CallRegisterFuncOrEmptyError      = mhttp.CallRegisterFuncOrEmptyError
+	CallRegisterFuncOrPlain           = mhttp.CallRegisterFuncOrPlain
 	NewResponseWrite                  = mhttp.NewResponseWrite
 	GetHTTPRespStatus                 = mhttp.GetHTTPRespStatus
+	GetWrittenStatus                  = mhttp.GetWrittenStatus
 	SetHTTPRespStatus                 = mhttp.SetHTTPRespStatus
 	UnWrapResponseWriter              = mhttp.UnWrapResponseWriter
+	ResponseStarted                   = mhttp.ResponseStarted
+	ResponseSize                      = mhttp.ResponseSize
 	CopyRequest                       = mhttp.CopyRequest
 	OK                                = mhttp.OK
 	Created                           = mhttp.Created
@@ -91,8 +154,42 @@ var (
 	LoopDetected                      = mhttp.LoopDetected
 	NotExtended                       = mhttp.NotExtended
 	NetworkAuthenticationRequired     = mhttp.NetworkAuthenticationRequired
+	FormatETag                        = mhttp.FormatETag
+	ParseETags                        = mhttp.ParseETags
+	ETagStrongMatch                   = mhttp.ETagStrongMatch
+	ETagWeakMatch                     = mhttp.ETagWeakMatch
+	CheckNotModified                  = mhttp.CheckNotModified
+	CheckPrecondition                 = mhttp.CheckPrecondition
+	RequirePrecondition               = mhttp.RequirePrecondition
+	IsPreconditionRequired            = mhttp.IsPreconditionRequired
+	ServeContent                      = mhttp.ServeContent
+	Stream                            = mhttp.Stream
 	CallRegisterFuncOrAbortEmptyError = mhttp.CallRegisterFuncOrAbortEmptyError
 	CallRegisterFuncOrAbortEmptyPlain = mhttp.CallRegisterFuncOrAbortEmptyPlain
 	CallRegisterFuncOrAbortError      = mhttp.CallRegisterFuncOrAbortError
 	CallRegisterFuncOrAbortPlain      = mhttp.CallRegisterFuncOrAbortPlain
+	CallRegisterFuncOrAbortEnvelope   = mhttp.CallRegisterFuncOrAbortEnvelope
+	DefaultEnvelope                   = mhttp.DefaultEnvelope
+	NewEnvelope                       = mhttp.NewEnvelope
+	SetEnvelope                       = mhttp.SetEnvelope
+	Envelope                          = mhttp.Envelope
+	JSONEnvelope                      = mhttp.JSONEnvelope
+	SetLangQueryKey                   = mhttp.SetLangQueryKey
+	SetLangCookieName                 = mhttp.SetLangCookieName
+	SetSupportedLangs                 = mhttp.SetSupportedLangs
+	SupportedLangs                    = mhttp.SupportedLangs
+	SetRequestLang                    = mhttp.SetRequestLang
+	RequestLang                       = mhttp.RequestLang
+	NegotiateLang                     = mhttp.NegotiateLang
+	LocalizeMessage                   = mhttp.LocalizeMessage
+	ErrorCodesHandler                 = mhttp.ErrorCodesHandler
+	SetProblemDetails                 = mhttp.SetProblemDetails
+	ProblemDetails                    = mhttp.ProblemDetails
+	SetProblemType                    = mhttp.SetProblemType
+	SetFieldErrors                    = mhttp.SetFieldErrors
+	GetFieldErrors                    = mhttp.GetFieldErrors
+	NewProblem                        = mhttp.NewProblem
+	ProblemJSON                       = mhttp.ProblemJSON
+	AbortProblemJSON                  = mhttp.AbortProblemJSON
+	IsProblem                         = mhttp.IsProblem
 )
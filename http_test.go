@@ -2,6 +2,7 @@ package mplus
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
@@ -344,6 +345,68 @@ func TestStatusCodeMethod(t *testing.T) {
 	}
 }
 
+func TestProblemDetails(t *testing.T) {
+	BeforeTest(true)
+	defer AfterTest(true)
+
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/orders/1", nil)
+	assert.False(t, IsProblem(r, MessageStatusNotFound))
+
+	SetProblemDetails(true)
+	defer SetProblemDetails(false)
+
+	assert.True(t, IsProblem(r, MessageStatusNotFound))
+	assert.False(t, IsProblem(r, MessageStatusOK))
+
+	// 状态方法
+	w := httptest.NewRecorder()
+	r = SetRequestHeaderRequestID(r, "req-1")
+	PreMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		NotFound(w, r)
+		assert.True(t, IsAbort(r))
+	}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, MIMEProblemJSON, w.Header().Get(HeaderContentType))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"instance":"/orders/1","request_id":"req-1"}`, w.Body.String())
+
+	// 携带错误码的消息
+	SetProblemType(func(r *http.Request, m message.Message) string { return "https://example.com/errors/order-exists" })
+	defer SetProblemType(nil)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/orders", nil)
+	PlusPlus(w, r.WithContext(NewContext(r.Context()))).AbortErrorMsg(NewErrCodeMessage(http.StatusConflict, 40902, "order already exists"))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"type":"https://example.com/errors/order-exists","title":"Conflict","status":409,"detail":"order already exists","instance":"/orders","code":40902}`, w.Body.String())
+
+	// 非异常状态码不受影响
+	w = httptest.NewRecorder()
+	PlusPlus(w, r.WithContext(NewContext(r.Context()))).ErrorMsg(NewMessage(http.StatusOK, "ok"))
+	assert.Equal(t, "ok\n", w.Body.String())
+
+	// 字段校验异常
+	type body struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/users", strings.NewReader(`{}`))
+	r = SetRequestHeader(r, HeaderContentType, MIMEJSON)
+	PreMiddleware(Bind((*body)(nil)).ServeHTTP).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, MIMEProblemJSON, w.Header().Get(HeaderContentType))
+
+	resp := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(http.StatusBadRequest), resp["status"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"field": "body.Name", "rule": "required", "message": "body.Name failed on tag 'required'",
+	}}, resp[ProblemFieldErrorsKey])
+}
+
 func TestRegisterHttpStatusMethod(t *testing.T) {
 
 	getRequest := func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil) }
@@ -675,3 +738,178 @@ func TestDumpRequestPure(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, content, string(bodyBytes))
 }
+
+func TestNegotiateLang(t *testing.T) {
+	assert.Equal(t, MSGType("zh-TW"), NegotiateLang("zh-TW,zh;q=0.9,en;q=0.8"))
+	assert.Equal(t, MSGType("en"), NegotiateLang("zh;q=0.5, en"))
+	assert.Equal(t, MSGType("zh"), NegotiateLang("zh-TW,zh;q=0.9,en;q=0.8", MSGLangEN, MSGLangZH))
+	assert.Equal(t, MSGType("en"), NegotiateLang("fr-CA,en-US;q=0.8", MSGLangZH, MSGLangEN))
+	assert.Equal(t, MSGType("zh"), NegotiateLang("fr, *;q=0.1", MSGLangZH, MSGLangEN))
+	assert.Equal(t, MSGType(""), NegotiateLang("fr, en;q=0", MSGLangZH, MSGLangEN))
+	assert.Equal(t, MSGType(""), NegotiateLang(""))
+}
+
+func TestRequestLang(t *testing.T) {
+	newRequest := func(url string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set(HeaderAcceptLanguage, "zh-TW,zh;q=0.9")
+		return r.WithContext(NewContext(r.Context()))
+	}
+
+	// Accept-Language
+	assert.Equal(t, MSGType("zh-TW"), RequestLang(newRequest("http://127.0.0.1")))
+
+	// cookie 优先于 Accept-Language
+	r := newRequest("http://127.0.0.1")
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "en-us"})
+	assert.Equal(t, MSGType("en-US"), RequestLang(r))
+
+	// query 优先于 cookie
+	r = newRequest("http://127.0.0.1?lang=ja")
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "en-us"})
+	assert.Equal(t, MSGType("ja"), RequestLang(r))
+
+	// 中间件指定的语言优先级最高
+	r = newRequest("http://127.0.0.1?lang=ja")
+	assert.Equal(t, MSGType("fr"), RequestLang(SetRequestLang(r, "fr")))
+}
+
+func TestLocalizeResponse(t *testing.T) {
+	m := NewErrCodeMessage(http.StatusConflict, 409301, "address exists").AddI18Message(MSGLangZH, "地址已存在")
+
+	r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1", nil)
+	r.Header.Set(HeaderAcceptLanguage, "zh-TW,zh;q=0.9,en;q=0.8")
+	r = r.WithContext(NewContext(r.Context()))
+
+	w := httptest.NewRecorder()
+	PlusPlus(w, r).AbortErrorMsg(m)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "地址已存在\n", w.Body.String())
+	assert.Equal(t, "地址已存在", PlusPlus(w, r).Text(m))
+	assert.Equal(t, "address exists", m.Default())
+
+	// 无法匹配的语言回退至默认语言
+	r = httptest.NewRequest(http.MethodPost, "http://127.0.0.1", nil)
+	r.Header.Set(HeaderAcceptLanguage, "fr")
+	r = r.WithContext(NewContext(r.Context()))
+
+	w = httptest.NewRecorder()
+	PlusPlus(w, r).ErrorMsg(m)
+	assert.Equal(t, "address exists\n", w.Body.String())
+}
+
+func TestErrorCodesHandler(t *testing.T) {
+	Messages.Add(NewErrCodeMessage(http.StatusTeapot, 418601, "no coffee"))
+
+	w := httptest.NewRecorder()
+	ErrorCodesHandler(NewResponseWrite(w), httptest.NewRequest(http.MethodGet, "http://127.0.0.1/errors", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get(HeaderContentType), MIMEJSON)
+	assert.Contains(t, w.Body.String(), `"code":418601`)
+
+	w = httptest.NewRecorder()
+	ErrorCodesHandler(NewResponseWrite(w), httptest.NewRequest(http.MethodGet, "http://127.0.0.1/errors?format=md", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get(HeaderContentType), ContentTypeMarkdown)
+	assert.Contains(t, w.Body.String(), "| 418601 | 418 I'm a teapot |")
+
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/errors", nil)
+	r.Header.Set(HeaderAccept, ContentTypeMarkdown)
+	w = httptest.NewRecorder()
+	ErrorCodesHandler(w, r)
+	assert.Contains(t, w.Body.String(), "| Code | Status |")
+}
+
+func TestStatusClassMethod(t *testing.T) {
+	hook := func(name string) StatusMethodCallback {
+		return func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+			w.WriteHeader(statusCode)
+			_, _ = w.Write([]byte(name))
+		}
+	}
+
+	hub := NewStatusMethodHub()
+	hub.Register(http.StatusNotFound, hook("exact"))
+	hub.RegisterClass(StatusClassClientError, hook("4xx"))
+
+	_, exists := hub.Lookup(http.StatusInternalServerError)
+	assert.False(t, exists)
+
+	hub.RegisterFallback(hook("fallback"))
+	assert.Equal(t, StatusClassServerError, StatusClassOf(http.StatusBadGateway))
+
+	app := NewApp()
+	app.HTTP.RegisterHttpStatusMethod(http.StatusNotFound, hook("exact"))
+	app.RegisterHttpStatusClassMethod(StatusClassClientError, hook("4xx"))
+	app.RegisterHttpStatusFallbackMethod(hook("fallback"))
+
+	tests := []struct {
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{handler: NotFound, status: http.StatusNotFound, body: "exact"},
+		{handler: BadRequest, status: http.StatusBadRequest, body: "4xx"},
+		{handler: Conflict, status: http.StatusConflict, body: "4xx"},
+		{handler: InternalServerError, status: http.StatusInternalServerError, body: "fallback"},
+		{handler: func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Error(http.StatusForbidden, "forbidden") }, status: http.StatusForbidden, body: "4xx"},
+		{handler: func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).EmptyError(http.StatusBadGateway) }, status: http.StatusBadGateway, body: "fallback"},
+		{handler: func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusTeapot, "teapot") }, status: http.StatusTeapot, body: "4xx"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		MRote().WithApp(app).HandlerFunc(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, tt.status, w.Code)
+		assert.Equal(t, tt.body, w.Body.String())
+	}
+
+	// 路由级的类别回调优先于全局注册的精确回调
+	w := httptest.NewRecorder()
+	MRote().HttpStatusClassMethod(StatusClassClientError, hook("route")).HandlerFunc(NotFound).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "route", w.Body.String())
+
+	// 兜底回调不作用于 2xx/3xx 响应
+	_, exists = hub.Lookup(http.StatusOK)
+	assert.False(t, exists)
+	_, exists = hub.Lookup(http.StatusFound)
+	assert.False(t, exists)
+
+	passThrough := []struct {
+		route   *Route
+		handler http.HandlerFunc
+		method  string
+		status  int
+		body    string
+	}{
+		{route: MRote().WithApp(app), handler: func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusOK, "ok") },
+			method: http.MethodGet, status: http.StatusOK, body: "ok"},
+		{route: MRote().WithApp(app), handler: func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).NoContent() },
+			method: http.MethodGet, status: http.StatusNoContent, body: ""},
+		{route: MRote().WithApp(app).Use(CORSMiddleware(CORSConfig{AllowOrigins: []string{"*"}})), handler: func(w http.ResponseWriter, r *http.Request) {},
+			method: http.MethodOptions, status: http.StatusNoContent, body: ""},
+	}
+
+	for _, tt := range passThrough {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tt.method, "/", nil)
+		r.Header.Set(HeaderOrigin, "https://example.com")
+		r.Header.Set(HeaderAccessControlRequestMethod, http.MethodPost)
+		tt.route.HandlerFunc(tt.handler).ServeHTTP(w, r)
+		assert.Equal(t, tt.status, w.Code)
+		assert.Equal(t, tt.body, w.Body.String())
+	}
+
+	// 未注册回调时保持原有行为
+	w = httptest.NewRecorder()
+	MRote().WithApp(NewApp()).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Plain(http.StatusAccepted, "accepted")
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "accepted", w.Body.String())
+}
@@ -8,18 +8,56 @@ type MSGType = message.MSGType
 type Message = message.Message
 type Callback = message.Callback
 type CallbackMessage = message.CallbackMessage
+type PluralForm = message.PluralForm
+type PluralRule = message.PluralRule
+type Catalog = message.Catalog
+type CatalogEntry = message.CatalogEntry
+type CatalogIssue = message.CatalogIssue
+type CatalogReport = message.CatalogReport
+type ExportEntry = message.ExportEntry
 
 const (
 	MSGLangZH = message.MSGLangZH
 	MSGLangEN = message.MSGLangEN
 )
 
+const (
+	PluralZero     = message.PluralZero
+	PluralOne      = message.PluralOne
+	PluralTwo      = message.PluralTwo
+	PluralFew      = message.PluralFew
+	PluralMany     = message.PluralMany
+	PluralOther    = message.PluralOther
+	PluralCountKey = message.PluralCountKey
+)
+
+const (
+	CatalogJSON = message.CatalogJSON
+	CatalogYAML = message.CatalogYAML
+	CatalogTOML = message.CatalogTOML
+)
+
 var (
 	Messages                                   = message.Messages
 	NewMessage                                 = message.NewMessage
 	NewCallbackMessage                         = message.NewCallbackMessage
 	NewErrCodeMessage                          = message.NewErrCodeMessage
 	SetDefaultLang                             = message.SetDefaultLang
+	StatusMessage                              = message.StatusMessage
+	DefaultLang                                = message.DefaultLang
+	ParseLang                                  = message.ParseLang
+	LangFallback                               = message.LangFallback
+	LangParents                                = message.LangParents
+	RenderMessage                              = message.Render
+	RegisterPluralRule                         = message.RegisterPluralRule
+	PluralFormOf                               = message.PluralFormOf
+	ErrCatalogFormat                           = message.ErrCatalogFormat
+	ParseCatalog                               = message.ParseCatalog
+	LoadCatalogFiles                           = message.LoadCatalogFiles
+	ReloadCatalog                              = message.ReloadCatalog
+	ExportLangs                                = message.ExportLangs
+	WriteMessagesJSON                          = message.WriteJSON
+	WriteMessagesMarkdown                      = message.WriteMarkdown
 	MessageStatusOK                            = message.MessageStatusOK
 	MessageStatusCreated                       = message.MessageStatusCreated
 	MessageStatusAccepted                      = message.MessageStatusAccepted
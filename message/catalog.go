@@ -0,0 +1,337 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// 支持的消息目录格式
+const (
+	CatalogJSON = "json"
+	CatalogYAML = "yaml"
+	CatalogTOML = "toml"
+)
+
+// ErrCatalogFormat 不支持的消息目录格式
+var ErrCatalogFormat = errors.New("catalog format not support")
+
+// CatalogEntry 消息目录中的一条消息
+type CatalogEntry struct {
+	// Code 错误码
+	Code int `json:"code" yaml:"code" toml:"code"`
+	// Status http 状态码
+	Status int `json:"status" yaml:"status" toml:"status"`
+	// Text 各语言的消息，支持 {name} 占位符
+	Text map[MSGType]string `json:"text" yaml:"text" toml:"text"`
+	// Plurals 各语言各复数形式的消息
+	Plurals map[MSGType]map[PluralForm]string `json:"plurals,omitempty" yaml:"plurals,omitempty" toml:"plurals,omitempty"`
+}
+
+// Catalog 消息目录
+//
+//	{
+//	  "languages": ["en", "zh"],
+//	  "messages": [
+//	    {"code": 400001, "status": 400, "text": {"en": "addr not exists", "zh": "地址不存在"}}
+//	  ]
+//	}
+type Catalog struct {
+	// Languages 需要检查翻译的语言，为空时使用目录中出现过的所有语言
+	Languages []MSGType `json:"languages,omitempty" yaml:"languages,omitempty" toml:"languages,omitempty"`
+	// Messages 消息列表
+	Messages []CatalogEntry `json:"messages" yaml:"messages" toml:"messages"`
+}
+
+// CatalogIssue 消息目录中存在的问题
+type CatalogIssue struct {
+	File   string
+	Code   int
+	Reason string
+}
+
+func (i CatalogIssue) String() string {
+	if i.File == "" {
+		return fmt.Sprintf("code %d: %s", i.Code, i.Reason)
+	}
+
+	return fmt.Sprintf("%s: code %d: %s", i.File, i.Code, i.Reason)
+}
+
+// CatalogReport 消息目录的校验结果
+type CatalogReport struct {
+	// Entries 消息数量
+	Entries int
+	// Duplicates 重复的错误码，包括与通过 Messages.Add 注册的错误码重复的情况
+	Duplicates []CatalogIssue
+	// Mismatches 错误码为 0 或状态码无效
+	Mismatches []CatalogIssue
+	// Warnings 不影响加载的问题，如错误码未以状态码开头（400001 对应 400 的约定）
+	Warnings []CatalogIssue
+	// Missing 各语言缺失翻译的错误码，语言经 ParseLang 规范化
+	Missing map[MSGType][]int
+}
+
+// Err 存在重复错误码或状态码无效时返回异常，警告及缺失翻译不视为异常
+func (r *CatalogReport) Err() error {
+	issues := append(append([]CatalogIssue{}, r.Duplicates...), r.Mismatches...)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		reasons = append(reasons, issue.String())
+	}
+
+	return errors.New("invalid catalog: " + strings.Join(reasons, "; "))
+}
+
+// ParseCatalog 按指定格式解析消息目录，format 为 CatalogJSON、CatalogYAML 或 CatalogTOML
+func ParseCatalog(data []byte, format string) (*Catalog, error) {
+	catalog := &Catalog{}
+
+	var err error
+	switch strings.ToLower(format) {
+	case CatalogJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(catalog)
+	case CatalogYAML, "yml":
+		err = yaml.UnmarshalStrict(data, catalog)
+	case CatalogTOML:
+		err = toml.Unmarshal(data, catalog)
+	default:
+		return nil, ErrCatalogFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Validate 校验消息目录
+func (c *Catalog) Validate() *CatalogReport {
+	report := c.validate(map[int]string{}, "")
+	report.Missing = c.missing()
+	return report
+}
+
+// validate 校验重复的错误码及状态码，seen 为已出现的错误码及其所在的文件
+func (c *Catalog) validate(seen map[int]string, file string) *CatalogReport {
+	report := &CatalogReport{Entries: len(c.Messages)}
+
+	for _, entry := range c.Messages {
+		if f, exists := seen[entry.Code]; exists {
+			report.Duplicates = append(report.Duplicates, CatalogIssue{File: file, Code: entry.Code, Reason: "duplicate code, first defined in " + strconv.Quote(f)})
+		} else {
+			seen[entry.Code] = file
+		}
+
+		if reason := checkCodeStatus(entry.Code, entry.Status); reason != "" {
+			report.Mismatches = append(report.Mismatches, CatalogIssue{File: file, Code: entry.Code, Reason: reason})
+		} else if !strings.HasPrefix(strconv.Itoa(entry.Code), strconv.Itoa(entry.Status)) {
+			report.Warnings = append(report.Warnings, CatalogIssue{File: file, Code: entry.Code, Reason: "code does not start with status " + strconv.Itoa(entry.Status)})
+		}
+	}
+
+	return report
+}
+
+// missing 获取各语言缺失翻译的错误码，语言经 ParseLang 规范化后比较，如 en_us 与 en-US 视为同一语言
+func (c *Catalog) missing() map[MSGType][]int {
+	translated := make([]map[MSGType]bool, len(c.Messages))
+	seen := map[MSGType]bool{}
+
+	for i, entry := range c.Messages {
+		translated[i] = map[MSGType]bool{}
+
+		for lang, text := range entry.Text {
+			seen[ParseLang(string(lang))] = true
+			if text != "" {
+				translated[i][ParseLang(string(lang))] = true
+			}
+		}
+
+		for lang, plurals := range entry.Plurals {
+			seen[ParseLang(string(lang))] = true
+			if plurals[PluralOther] != "" {
+				translated[i][ParseLang(string(lang))] = true
+			}
+		}
+	}
+
+	var languages []MSGType
+	if len(c.Languages) != 0 {
+		languages = uniqueLanguages(append([]MSGType(nil), c.Languages...))
+	} else {
+		for lang := range seen {
+			languages = append(languages, lang)
+		}
+	}
+
+	missing := map[MSGType][]int{}
+	for _, lang := range languages {
+		for i, entry := range c.Messages {
+			if !translated[i][lang] {
+				missing[lang] = append(missing[lang], entry.Code)
+			}
+		}
+
+		sort.Ints(missing[lang])
+	}
+
+	return missing
+}
+
+// BuildMessages 根据消息目录构造消息体
+func (c *Catalog) BuildMessages() []Message {
+	ms := make([]Message, 0, len(c.Messages))
+
+	for _, entry := range c.Messages {
+		m := NewErrCodeMessage(entry.Status, entry.Code, "")
+
+		for lang, text := range entry.Text {
+			m.AddI18Message(ParseLang(string(lang)), text)
+		}
+
+		for lang, plurals := range entry.Plurals {
+			for form, text := range plurals {
+				m.AddI18PluralMessage(ParseLang(string(lang)), form, text)
+			}
+		}
+
+		ms = append(ms, m)
+	}
+
+	return ms
+}
+
+func checkCodeStatus(code, status int) string {
+	if code == 0 {
+		return "code must not be 0"
+	}
+
+	if http.StatusText(status) == "" {
+		return "invalid status " + strconv.Itoa(status)
+	}
+
+	return ""
+}
+
+var (
+	catalogLock  sync.Mutex
+	catalogFiles []string
+	catalogCodes = map[int]bool{}
+)
+
+// LoadCatalogFiles 加载消息目录文件至 Messages，文件格式根据扩展名（.json、.yaml、.yml、.toml）确定
+//
+// 所有文件校验通过后才会替换上一次加载的消息目录，替换过程中 Messages.Get 不会获取到不完整的目录；
+// 存在重复错误码或状态码无效时返回异常且不会修改 Messages，警告及缺失的翻译通过 CatalogReport.Warnings 及 CatalogReport.Missing 获取
+func LoadCatalogFiles(paths ...string) (*CatalogReport, error) {
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+
+	report, err := loadCatalogFiles(paths)
+	if err == nil {
+		catalogFiles = append([]string{}, paths...)
+	}
+
+	return report, err
+}
+
+// ReloadCatalog 重新加载上一次通过 LoadCatalogFiles 成功加载的消息目录文件，加载失败时保留当前消息目录
+func ReloadCatalog() (*CatalogReport, error) {
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+
+	return loadCatalogFiles(catalogFiles)
+}
+
+func loadCatalogFiles(paths []string) (*CatalogReport, error) {
+	merged := &Catalog{}
+	report := &CatalogReport{}
+	seen := map[int]string{}
+
+	// 通过 Messages.Add 注册的错误码不允许被消息目录覆盖
+	lock.RLock()
+	for code := range Messages {
+		if !catalogCodes[code] {
+			seen[code] = "Messages.Add"
+		}
+	}
+	lock.RUnlock()
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		catalog, err := ParseCatalog(data, strings.TrimPrefix(filepath.Ext(path), "."))
+		if err != nil {
+			return nil, fmt.Errorf("parse catalog %s failed: %v", path, err)
+		}
+
+		fileReport := catalog.validate(seen, path)
+		report.Entries += fileReport.Entries
+		report.Duplicates = append(report.Duplicates, fileReport.Duplicates...)
+		report.Mismatches = append(report.Mismatches, fileReport.Mismatches...)
+		report.Warnings = append(report.Warnings, fileReport.Warnings...)
+
+		merged.Languages = append(merged.Languages, catalog.Languages...)
+		merged.Messages = append(merged.Messages, catalog.Messages...)
+	}
+
+	merged.Languages = uniqueLanguages(merged.Languages)
+	report.Missing = merged.missing()
+
+	if err := report.Err(); err != nil {
+		return report, err
+	}
+
+	ms := merged.BuildMessages()
+
+	lock.Lock()
+	for code := range catalogCodes {
+		delete(Messages, code)
+	}
+
+	catalogCodes = make(map[int]bool, len(ms))
+	for _, m := range ms {
+		Messages[m.ErrCode()] = m
+		catalogCodes[m.ErrCode()] = true
+	}
+	lock.Unlock()
+
+	return report, nil
+}
+
+// uniqueLanguages 经 ParseLang 规范化后去重
+func uniqueLanguages(languages []MSGType) []MSGType {
+	seen := map[MSGType]bool{}
+	unique := languages[:0]
+
+	for _, lang := range languages {
+		if lang = ParseLang(string(lang)); !seen[lang] {
+			seen[lang] = true
+			unique = append(unique, lang)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,149 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportEntry 导出的消息，在 CatalogEntry 的基础上标识是否注册了处理回调
+type ExportEntry struct {
+	CatalogEntry
+	// Callback 是否注册了处理回调
+	Callback bool `json:"callback" yaml:"callback" toml:"callback"`
+}
+
+// Export 导出 Messages 中的所有消息，按错误码升序排列
+func (ms messages) Export() []ExportEntry {
+	lock.RLock()
+	list := make([]Message, 0, len(ms))
+	for _, m := range ms {
+		list = append(list, m)
+	}
+	lock.RUnlock()
+
+	entries := make([]ExportEntry, 0, len(list))
+	for _, m := range list {
+		entry := ExportEntry{
+			CatalogEntry: CatalogEntry{Code: m.ErrCode(), Status: m.Status(), Text: m.Texts()},
+			Callback:     m.HasCallback(),
+		}
+
+		if plurals := m.Plurals(); len(plurals) != 0 {
+			entry.Plurals = plurals
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+
+	return entries
+}
+
+// ExportLangs 获取导出消息中出现的所有语言，默认语言在前，其余按字母序排列
+func ExportLangs(entries []ExportEntry) []MSGType {
+	seen := map[MSGType]bool{}
+	for _, entry := range entries {
+		for lang := range entry.Text {
+			seen[lang] = true
+		}
+
+		for lang := range entry.Plurals {
+			seen[lang] = true
+		}
+	}
+
+	langs := make([]MSGType, 0, len(seen))
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+
+	sort.Slice(langs, func(i, j int) bool {
+		if langs[i] == defaultLang || langs[j] == defaultLang {
+			return langs[i] == defaultLang
+		}
+		return langs[i] < langs[j]
+	})
+
+	return langs
+}
+
+// WriteJSON 以 JSON 格式输出导出的消息
+func WriteJSON(w io.Writer, entries []ExportEntry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(entries)
+}
+
+// WriteMarkdown 以 Markdown 表格输出导出的消息，每种语言一列，复数形式以 "form: text" 的方式列出
+//
+//	| Code | Status | en | zh | Callback |
+//	| --- | --- | --- | --- | --- |
+//	| 400001 | 400 Bad Request | addr not exists | 地址不存在 | no |
+func WriteMarkdown(w io.Writer, entries []ExportEntry) error {
+	langs := ExportLangs(entries)
+
+	head := []string{"Code", "Status"}
+	for _, lang := range langs {
+		head = append(head, string(lang))
+	}
+	head = append(head, "Callback")
+
+	rows := [][]string{head, make([]string, len(head))}
+	for i := range rows[1] {
+		rows[1][i] = "---"
+	}
+
+	for _, entry := range entries {
+		row := []string{strconv.Itoa(entry.Code), strings.TrimSpace(fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)))}
+
+		for _, lang := range langs {
+			row = append(row, markdownCell(entryText(entry, lang)))
+		}
+
+		callback := "no"
+		if entry.Callback {
+			callback = "yes"
+		}
+
+		rows = append(rows, append(row, callback))
+	}
+
+	for _, row := range rows {
+		if _, err := io.WriteString(w, "| "+strings.Join(row, " | ")+" |\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryText 获取消息指定语言的内容，存在复数形式时依次列出
+func entryText(entry ExportEntry, lang MSGType) string {
+	texts := []string{}
+	if text := entry.Text[lang]; text != "" {
+		texts = append(texts, text)
+	}
+
+	forms := entry.Plurals[lang]
+	for _, form := range []PluralForm{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther} {
+		if text, exists := forms[form]; exists {
+			texts = append(texts, string(form)+": "+text)
+		}
+	}
+
+	return strings.Join(texts, "<br>")
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func markdownCell(text string) string {
+	return markdownReplacer.Replace(text)
+}
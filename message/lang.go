@@ -0,0 +1,83 @@
+package message
+
+import (
+	"strings"
+)
+
+// ParseLang 将 BCP 47 语言标签规范化为 MSGType，如 zh_tw、ZH-tw 均规范化为 zh-TW，zh-hant-tw 规范化为 zh-Hant-TW
+func ParseLang(tag string) MSGType {
+	tag = strings.TrimSpace(strings.Replace(tag, "_", "-", -1))
+	if tag == "" {
+		return ""
+	}
+
+	subtags := strings.Split(tag, "-")
+	for i, subtag := range subtags {
+		switch {
+		case i == 0:
+			subtags[i] = strings.ToLower(subtag)
+		case len(subtag) == 2 && isAlpha(subtag): // region
+			subtags[i] = strings.ToUpper(subtag)
+		case len(subtag) == 4 && isAlpha(subtag): // script
+			subtags[i] = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			subtags[i] = strings.ToLower(subtag)
+		}
+	}
+
+	return MSGType(strings.Join(subtags, "-"))
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// LangFallback 获取语言的回退链，依次移除最后一个子标签，最后回退至默认语言及英文，如 zh-Hant-TW → zh-Hant → zh → en
+func LangFallback(lang MSGType) []MSGType {
+	var chain []MSGType
+
+	add := func(l MSGType) {
+		for _, exists := range chain {
+			if exists == l {
+				return
+			}
+		}
+		chain = append(chain, l)
+	}
+
+	for _, l := range LangParents(lang) {
+		add(l)
+	}
+
+	add(defaultLang)
+	add(MSGLangEN)
+
+	return chain
+}
+
+// LangParents 获取语言自身及其各级上级语言，不包含默认语言，如 zh-Hant-TW → zh-Hant → zh
+func LangParents(lang MSGType) []MSGType {
+	var parents []MSGType
+
+	for tag := string(ParseLang(string(lang))); tag != ""; {
+		parents = append(parents, MSGType(tag))
+
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+
+	return parents
+}
+
+// DefaultLang 获取当前项目默认的语言
+func DefaultLang() MSGType {
+	return defaultLang
+}
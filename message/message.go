@@ -21,17 +21,42 @@ type message struct {
 	ErrorCode  int
 
 	Callback
+	hasCallback bool
+
+	cause error
+	data  interface{}
+	args  map[string]interface{}
+	lang  MSGType
 
 	lock       sync.Mutex
 	MessageStr map[MSGType]string
+	plurals    map[MSGType]map[PluralForm]string
 }
 
 // Message 消息体
 type Message interface {
 	// AddI18Message 新增一个指定语言的消息
 	AddI18Message(msgType MSGType, message string) Message
-	// I18nMessage 获取一个指定语言的消息
+	// I18nMessage 获取一个指定语言的消息，通过 WithArgs 附带模板参数时返回渲染后的消息
 	I18nMessage(msgType MSGType) string
+	// AddI18PluralMessage 新增一个指定语言及复数形式的消息，模板参数中存在 PluralCountKey 时根据其数量选择对应的复数形式
+	AddI18PluralMessage(msgType MSGType, form PluralForm, message string) Message
+	// Template 获取指定语言未经渲染的消息模板
+	Template(msgType MSGType) string
+	// Texts 获取所有语言未经渲染的消息模板
+	Texts() map[MSGType]string
+	// Plurals 获取所有语言各复数形式的消息模板
+	Plurals() map[MSGType]map[PluralForm]string
+	// WithArgs 获取附带模板参数的消息拷贝，消息中的 {name} 占位符将在获取消息时渲染，不会修改当前消息
+	WithArgs(args map[string]interface{}) Message
+	// Args 获取通过 WithArgs 附带的模板参数
+	Args() map[string]interface{}
+	// Localize 获取指定语言的消息拷贝，拷贝的 Default 将按 LangFallback 的回退链返回第一个存在的消息，不会修改当前消息
+	Localize(lang MSGType) Message
+	// Lang 获取通过 Localize 指定的语言
+	Lang() MSGType
+	// Text 按 LangFallback 的回退链获取第一个存在的消息
+	Text(lang MSGType) string
 	// SetStatus 设置当前消息的状态嘛
 	SetStatus(statusCode int) Message
 	// Status 获取当前消息的状态码
@@ -50,6 +75,21 @@ type Message interface {
 	Set(string) Message
 	// SetEn 设置英文消息
 	SetEn(string) Message
+	// HasCallback 当前消息是否注册了处理回调
+	HasCallback() bool
+	// WithCause 获取附带异常原因的消息拷贝，不会修改当前消息
+	WithCause(err error) Message
+	// WithData 获取附带响应数据的消息拷贝，不会修改当前消息
+	WithData(data interface{}) Message
+	// Data 获取通过 WithData 附带的响应数据
+	Data() interface{}
+
+	// Error 实现 error，消息内容为默认语言类型消息，附带异常原因时追加异常原因
+	Error() string
+	// Unwrap 获取通过 WithCause 附带的异常原因
+	Unwrap() error
+	// Is 判断 target 是否为同一消息，错误码相同即视为同一消息，错误码均为 0 时比较状态码
+	Is(target error) bool
 
 	Callback
 }
@@ -69,6 +109,7 @@ func NewCallbackMessage(statusCode, errorCode int, en string, back CallbackMessa
 
 	if back != nil {
 		m.Callback = back
+		m.hasCallback = true
 	} else {
 		m.Callback = EmptyCallback // prevent panic
 	}
@@ -109,6 +150,8 @@ func NewMessage(statusCode int, en string) Message {
 }
 
 func (m *message) AddI18Message(msgType MSGType, message string) Message {
+	msgType = ParseLang(string(msgType))
+
 	m.lock.Lock()
 	m.MessageStr[msgType] = message
 	m.lock.Unlock()
@@ -116,17 +159,130 @@ func (m *message) AddI18Message(msgType MSGType, message string) Message {
 }
 
 func (m *message) I18nMessage(msgType MSGType) string {
+	msgType = ParseLang(string(msgType))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if len(m.args) == 0 {
+		return m.MessageStr[msgType]
+	}
+
+	return Render(m.template(msgType), m.args)
+}
+
+// template 获取指定语言的消息模板，优先使用模板参数数量对应的复数形式，不存在时依次使用基础模板及 PluralOther
+func (m *message) template(msgType MSGType) string {
+	plurals := m.plurals[msgType]
+
+	if n, ok := pluralCount(m.args); ok && len(plurals) != 0 {
+		if tpl, exists := plurals[PluralFormOf(msgType, n)]; exists {
+			return tpl
+		}
+	}
+
+	if tpl, exists := m.MessageStr[msgType]; exists {
+		return tpl
+	}
+
+	return plurals[PluralOther]
+}
+
+func (m *message) AddI18PluralMessage(msgType MSGType, form PluralForm, message string) Message {
+	msgType = ParseLang(string(msgType))
+
+	m.lock.Lock()
+	if m.plurals == nil {
+		m.plurals = map[MSGType]map[PluralForm]string{}
+	}
+
+	if m.plurals[msgType] == nil {
+		m.plurals[msgType] = map[PluralForm]string{}
+	}
+
+	m.plurals[msgType][form] = message
+	m.lock.Unlock()
+	return m
+}
+
+func (m *message) Template(msgType MSGType) string {
+	msgType = ParseLang(string(msgType))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
 	return m.MessageStr[msgType]
 }
 
+func (m *message) Texts() map[MSGType]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	texts := make(map[MSGType]string, len(m.MessageStr))
+	for msgType, msg := range m.MessageStr {
+		texts[msgType] = msg
+	}
+
+	return texts
+}
+
+func (m *message) Plurals() map[MSGType]map[PluralForm]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	plurals := make(map[MSGType]map[PluralForm]string, len(m.plurals))
+	for msgType, forms := range m.plurals {
+		plurals[msgType] = make(map[PluralForm]string, len(forms))
+		for form, msg := range forms {
+			plurals[msgType][form] = msg
+		}
+	}
+
+	return plurals
+}
+
+func (m *message) WithArgs(args map[string]interface{}) Message {
+	_m := m.Copy().(*message)
+	_m.args = args
+	return _m
+}
+
+func (m *message) Args() map[string]interface{} {
+	return m.args
+}
+
 func (m *message) En() string {
 	return m.I18nMessage(MSGLangEN)
 }
 
+// Default 获取默认语言类型消息，通过 Localize 指定语言时按指定语言的回退链获取
 func (m *message) Default() string {
+	if m.lang != "" {
+		return m.Text(m.lang)
+	}
+
 	return m.I18nMessage(defaultLang)
 }
 
+func (m *message) Text(lang MSGType) string {
+	for _, l := range LangFallback(lang) {
+		if msg := m.I18nMessage(l); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func (m *message) Localize(lang MSGType) Message {
+	_m := m.Copy().(*message)
+	_m.lang = ParseLang(string(lang))
+	return _m
+}
+
+func (m *message) Lang() MSGType {
+	return m.lang
+}
+
 func (m *message) Set(value string) Message {
 	return m.AddI18Message(defaultLang, value)
 }
@@ -145,8 +301,85 @@ func (m *message) SetStatus(statusCode int) Message {
 	return m
 }
 
+// Copy 拷贝当前消息，包括所有语言的消息、复数形式、回调、模板参数、指定的语言、异常原因及响应数据
 func (m *message) Copy() Message {
-	return NewCallbackMessage(m.Status(), m.ErrCode(), m.Default(), m.Callback.(CallbackMessage))
+	_m := &message{
+		MessageStr: map[MSGType]string{},
+		StatusCode: m.Status(), ErrorCode: m.ErrCode(),
+		Callback: m.Callback, hasCallback: m.hasCallback,
+	}
+
+	if _m.Callback == nil {
+		_m.Callback = EmptyCallback // prevent panic
+	}
+
+	_m.cause = m.cause
+	_m.data = m.data
+	_m.args = m.args
+	_m.lang = m.lang
+
+	m.lock.Lock()
+	for msgType, msg := range m.MessageStr {
+		_m.MessageStr[msgType] = msg
+	}
+
+	for msgType, plurals := range m.plurals {
+		for form, msg := range plurals {
+			_m.AddI18PluralMessage(msgType, form, msg)
+		}
+	}
+	m.lock.Unlock()
+
+	return _m
+}
+
+func (m *message) WithCause(err error) Message {
+	_m := m.Copy().(*message)
+	_m.cause = err
+	return _m
+}
+
+func (m *message) WithData(data interface{}) Message {
+	_m := m.Copy().(*message)
+	_m.data = data
+	return _m
+}
+
+func (m *message) Data() interface{} {
+	return m.data
+}
+
+func (m *message) Error() string {
+	if m.cause == nil {
+		return m.Default()
+	}
+
+	if m.Default() == "" {
+		return m.cause.Error()
+	}
+
+	return m.Default() + ": " + m.cause.Error()
+}
+
+func (m *message) Unwrap() error {
+	return m.cause
+}
+
+func (m *message) Is(target error) bool {
+	t, ok := target.(Message)
+	if !ok {
+		return false
+	}
+
+	if m.ErrCode() != 0 || t.ErrCode() != 0 {
+		return m.ErrCode() == t.ErrCode()
+	}
+
+	return m.Status() == t.Status()
+}
+
+func (m *message) HasCallback() bool {
+	return m.hasCallback
 }
 
 func (m *message) Status() int {
@@ -166,7 +399,7 @@ type messages map[int]Message
 
 // Messages Message 消息集合
 var (
-	lock     = sync.Mutex{}
+	lock     = sync.RWMutex{}
 	Messages = messages{}
 )
 
@@ -179,9 +412,20 @@ func (ms messages) Add(msg Message) {
 
 // Get 获取指定 Message
 func (ms messages) Get(errCode int) Message {
+	lock.RLock()
+	defer lock.RUnlock()
 	return ms[errCode]
 }
 
+// StatusMessage 获取指定状态码对应的通用型话术，不存在时返回一个新的消息体
+func StatusMessage(statusCode int) Message {
+	if m, exists := statusMessages[statusCode]; exists {
+		return m
+	}
+
+	return NewMessage(statusCode, http.StatusText(statusCode))
+}
+
 // SetDefaultLang 设置当前项目默认的语言
 func SetDefaultLang(msgType MSGType) {
 	defaultLang = msgType
@@ -251,3 +495,33 @@ var (
 	MessageStatusNotExtended                   = NewMessage(http.StatusNotExtended, http.StatusText(http.StatusNotExtended))                                     // 510 Not Extended
 	MessageStatusNetworkAuthenticationRequired = NewMessage(http.StatusNetworkAuthenticationRequired, http.StatusText(http.StatusNetworkAuthenticationRequired)) // 511 Network Authentication Required
 )
+
+// statusMessages 状态码与通用型话术的映射
+var statusMessages = map[int]Message{}
+
+func init() {
+	for _, m := range []Message{
+		MessageStatusOK, MessageStatusCreated, MessageStatusAccepted, MessageStatusNonAuthoritativeInfo,
+		MessageStatusNoContent, MessageStatusResetContent, MessageStatusPartialContent, MessageStatusMultiStatus,
+		MessageStatusAlreadyReported, MessageStatusIMUsed,
+
+		MessageStatusMultipleChoices, MessageStatusMovedPermanently, MessageStatusFound, MessageStatusSeeOther,
+		MessageStatusNotModified, MessageStatusUseProxy, MessageStatusTemporaryRedirect, MessageStatusPermanentRedirect,
+
+		MessageStatusBadRequest, MessageStatusUnauthorized, MessageStatusPaymentRequired, MessageStatusForbidden,
+		MessageStatusNotFound, MessageStatusMethodNotAllowed, MessageStatusNotAcceptable, MessageStatusProxyAuthRequired,
+		MessageStatusRequestTimeout, MessageStatusConflict, MessageStatusGone, MessageStatusLengthRequired,
+		MessageStatusPreconditionFailed, MessageStatusRequestEntityTooLarge, MessageStatusRequestURITooLong,
+		MessageStatusUnsupportedMediaType, MessageStatusRequestedRangeNotSatisfiable, MessageStatusExpectationFailed,
+		MessageStatusTeapot, MessageStatusMisdirectedRequest, MessageStatusUnprocessableEntity, MessageStatusLocked,
+		MessageStatusFailedDependency, MessageStatusTooEarly, MessageStatusUpgradeRequired, MessageStatusPreconditionRequired,
+		MessageStatusTooManyRequests, MessageStatusRequestHeaderFieldsTooLarge, MessageStatusUnavailableForLegalReasons,
+
+		MessageStatusInternalServerError, MessageStatusNotImplemented, MessageStatusBadGateway, MessageStatusServiceUnavailable,
+		MessageStatusGatewayTimeout, MessageStatusHTTPVersionNotSupported, MessageStatusVariantAlsoNegotiates,
+		MessageStatusInsufficientStorage, MessageStatusLoopDetected, MessageStatusNotExtended,
+		MessageStatusNetworkAuthenticationRequired,
+	} {
+		statusMessages[m.Status()] = m
+	}
+}
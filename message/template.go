@@ -0,0 +1,157 @@
+package message
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PluralForm 复数形式，see CLDR plural rules
+type PluralForm string
+
+// 支持的复数形式
+const (
+	PluralZero  PluralForm = "zero"
+	PluralOne   PluralForm = "one"
+	PluralTwo   PluralForm = "two"
+	PluralFew   PluralForm = "few"
+	PluralMany  PluralForm = "many"
+	PluralOther PluralForm = "other"
+)
+
+// PluralCountKey 模板参数中用于选择复数形式的数量字段
+const PluralCountKey = "count"
+
+// PluralRule 根据数量获取指定语言的复数形式
+type PluralRule func(n float64) PluralForm
+
+var (
+	pluralRulesLock sync.RWMutex
+	pluralRules     = map[MSGType]PluralRule{
+		MSGLangEN: func(n float64) PluralForm {
+			if n == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+		MSGLangZH: func(n float64) PluralForm { return PluralOther },
+	}
+)
+
+// RegisterPluralRule 注册指定语言的复数规则，未注册规则的语言数量为 1 时使用 PluralOne，否则使用 PluralOther
+func RegisterPluralRule(msgType MSGType, rule PluralRule) {
+	pluralRulesLock.Lock()
+	pluralRules[ParseLang(string(msgType))] = rule
+	pluralRulesLock.Unlock()
+}
+
+// PluralFormOf 获取指定语言下数量 n 对应的复数形式，指定语言未注册规则时依次使用其上级语言的规则，如 zh-TW 使用 zh 的规则
+func PluralFormOf(msgType MSGType, n float64) PluralForm {
+	pluralRulesLock.RLock()
+	defer pluralRulesLock.RUnlock()
+
+	for _, lang := range LangParents(msgType) {
+		if rule, exists := pluralRules[lang]; exists {
+			return rule(n)
+		}
+	}
+
+	if n == 1 {
+		return PluralOne
+	}
+
+	return PluralOther
+}
+
+// Render 使用 args 渲染模板中的 {name} 占位符，未提供的占位符保持原样，{{ 及 }} 分别输出 { 及 }
+//
+//	Render("field {field} must be at least {min} characters", map[string]interface{}{"field": "name", "min": 3})
+//	// field name must be at least 3 characters
+func Render(template string, args map[string]interface{}) string {
+	if !strings.ContainsAny(template, "{}") {
+		return template
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(template))
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+
+		if c == '}' && i+1 < len(template) && template[i+1] == '}' {
+			builder.WriteByte('}')
+			i++
+			continue
+		}
+
+		if c != '{' {
+			builder.WriteByte(c)
+			continue
+		}
+
+		if i+1 < len(template) && template[i+1] == '{' {
+			builder.WriteByte('{')
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(template[i+1:], '}')
+		if end < 0 {
+			builder.WriteString(template[i:])
+			break
+		}
+
+		name := template[i+1 : i+1+end]
+		if value, exists := args[strings.TrimSpace(name)]; exists {
+			builder.WriteString(fmt.Sprint(value))
+		} else {
+			builder.WriteString(template[i : i+2+end])
+		}
+
+		i += end + 1
+	}
+
+	return builder.String()
+}
+
+// pluralCount 获取模板参数中的数量，不存在或无法转换为数值时返回 false
+func pluralCount(args map[string]interface{}) (float64, bool) {
+	value, exists := args[PluralCountKey]
+	if !exists {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil && !math.IsNaN(n)
+	}
+
+	return 0, false
+}
@@ -1,11 +1,17 @@
 package mplus
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	assert "github.com/stretchr/testify/require"
 	"github.com/tangzixiang/mplus/message"
 )
@@ -159,7 +165,52 @@ func Test_message_Copy(t *testing.T) {
 	assert.Equal(t, http.StatusText(http.StatusBadRequest), m2.I18nMessage(MSGLangEN))
 	assert.Equal(t, "", m2.I18nMessage(MSGLangZH))
 	assert.Equal(t, 400001, m2.ErrCode())
+	assert.False(t, m2.HasCallback())
 
+	// with callback
+	called := false
+	m3 := NewCallbackMessage(http.StatusBadRequest, 400002, "bad", func(w http.ResponseWriter, r *http.Request, m Message, respData interface{}) {
+		called = true
+	}).Copy()
+
+	assert.True(t, m3.HasCallback())
+	m3.Do(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), m3, nil)
+	assert.True(t, called)
+}
+
+func Test_message_Error(t *testing.T) {
+	m := NewErrCodeMessage(http.StatusNotFound, 404001, "order not found").AddI18Message(MSGLangZH, "订单不存在")
+
+	var err error = m
+	assert.Equal(t, "order not found", err.Error())
+	assert.Nil(t, m.Unwrap())
+
+	// 附带异常原因
+	cause := errors.New("sql: no rows in result set")
+	mc := m.WithCause(cause)
+
+	assert.Equal(t, "order not found: sql: no rows in result set", mc.Error())
+	assert.Equal(t, cause, mc.Unwrap())
+	assert.Equal(t, "订单不存在", mc.I18nMessage(MSGLangZH))
+	assert.Nil(t, m.Unwrap()) // 原消息不受影响
+
+	// 按错误码匹配
+	assert.True(t, mc.Is(m))
+	assert.False(t, mc.Is(NewErrCodeMessage(http.StatusNotFound, 404002, "user not found")))
+	assert.False(t, mc.Is(cause))
+	assert.True(t, MessageStatusNotFound.WithCause(cause).Is(MessageStatusNotFound))
+	assert.False(t, MessageStatusNotFound.Is(MessageStatusBadRequest))
+
+	// 通过 github.com/pkg/errors 包装后依旧可以取回消息
+	target, ok := errors.Cause(errors.Wrap(mc, "load order")).(Message)
+	assert.True(t, ok)
+	assert.Equal(t, 404001, target.ErrCode())
+	assert.Equal(t, cause, target.Unwrap())
+
+	// 附带响应数据
+	md := m.WithData(Data{"id": 1})
+	assert.Equal(t, Data{"id": 1}, md.Data())
+	assert.Nil(t, m.Data())
 }
 
 func Test_message_SetErrCode(t *testing.T) {
@@ -215,3 +266,226 @@ func TestCallbackMessage_Do(t *testing.T) {
 	assert.False(t, IsAbort(r))
 	assert.NotEqual(t, http.StatusBadRequest, GetHTTPRespStatus(w))
 }
+
+func TestRenderMessage(t *testing.T) {
+	args := map[string]interface{}{"field": "name", "min": 3}
+
+	assert.Equal(t, "field name must be at least 3 characters", RenderMessage("field {field} must be at least {min} characters", args))
+	assert.Equal(t, "field name must be {max}", RenderMessage("field { field } must be {max}", args))
+	assert.Equal(t, "{field} is name", RenderMessage("{{field}} is {field}", args))
+	assert.Equal(t, "unclosed {field", RenderMessage("unclosed {field", args))
+	assert.Equal(t, "static", RenderMessage("static", nil))
+}
+
+func Test_message_WithArgs(t *testing.T) {
+	m := NewErrCodeMessage(http.StatusBadRequest, 400101, "field {field} must be at least {min} characters").
+		AddI18Message(MSGLangZH, "字段 {field} 至少需要 {min} 个字符")
+
+	mr := m.WithArgs(map[string]interface{}{"field": "name", "min": 3})
+
+	assert.Equal(t, "field name must be at least 3 characters", mr.En())
+	assert.Equal(t, "字段 name 至少需要 3 个字符", mr.I18nMessage(MSGLangZH))
+	assert.Equal(t, "field {field} must be at least {min} characters", mr.Template(MSGLangEN))
+	assert.Equal(t, "field {field} must be at least {min} characters", m.En()) // 原消息不受影响
+	assert.Equal(t, "field name must be at least 3 characters", mr.Error())
+}
+
+func Test_message_AddI18PluralMessage(t *testing.T) {
+	m := NewErrCodeMessage(http.StatusBadRequest, 400102, "").
+		AddI18PluralMessage(MSGLangEN, PluralOne, "{count} item is out of stock").
+		AddI18PluralMessage(MSGLangEN, PluralOther, "{count} items are out of stock").
+		AddI18Message(MSGLangZH, "{count} 件商品缺货")
+
+	assert.Equal(t, "1 item is out of stock", m.WithArgs(map[string]interface{}{"count": 1}).En())
+	assert.Equal(t, "3 items are out of stock", m.WithArgs(map[string]interface{}{"count": 3}).En())
+	assert.Equal(t, "0 items are out of stock", m.WithArgs(map[string]interface{}{"count": "0"}).En())
+	assert.Equal(t, "1 件商品缺货", m.WithArgs(map[string]interface{}{"count": 1}).I18nMessage(MSGLangZH))
+
+	// 自定义复数规则
+	RegisterPluralRule("ar", func(n float64) PluralForm {
+		if n == 2 {
+			return PluralTwo
+		}
+		return PluralOther
+	})
+
+	m.AddI18PluralMessage("ar", PluralTwo, "two").AddI18PluralMessage("ar", PluralOther, "other")
+	assert.Equal(t, "two", m.WithArgs(map[string]interface{}{"count": 2}).I18nMessage("ar"))
+	assert.Equal(t, "other", m.WithArgs(map[string]interface{}{"count": 1}).I18nMessage("ar"))
+	assert.Equal(t, "two", m.WithArgs(map[string]interface{}{"count": 2}).Copy().I18nMessage("ar"))
+}
+
+func TestParseCatalog(t *testing.T) {
+	for _, file := range []string{"orders.json", "users.yaml", "stock.toml"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "catalog", file))
+		assert.Nil(t, err)
+
+		catalog, err := ParseCatalog(data, strings.TrimPrefix(filepath.Ext(file), "."))
+		assert.Nil(t, err, file)
+		assert.Equal(t, []MSGType{MSGLangEN, MSGLangZH}, catalog.Languages, file)
+		assert.NotEmpty(t, catalog.Messages, file)
+		assert.Nil(t, catalog.Validate().Err(), file)
+	}
+
+	_, err := ParseCatalog([]byte(`{}`), "xml")
+	assert.Equal(t, ErrCatalogFormat, err)
+
+	// 重复错误码及状态码不匹配
+	catalog, err := ParseCatalog([]byte(`{"messages":[
+		{"code":400301,"status":400,"text":{"en":"a"}},
+		{"code":400301,"status":400,"text":{"en":"b","zh":"b"}},
+		{"code":500301,"status":400,"text":{"en":"c"}},
+		{"code":600301,"status":600,"text":{"en":"d"}}
+	]}`), CatalogJSON)
+	assert.Nil(t, err)
+
+	report := catalog.Validate()
+	assert.Len(t, report.Duplicates, 1)
+	assert.Equal(t, 400301, report.Duplicates[0].Code)
+	assert.Len(t, report.Mismatches, 1)
+	assert.Equal(t, 600301, report.Mismatches[0].Code)
+	assert.Len(t, report.Warnings, 1)
+	assert.Equal(t, 500301, report.Warnings[0].Code)
+	assert.Equal(t, []int{400301, 500301, 600301}, report.Missing[MSGLangZH])
+	assert.Empty(t, report.Missing[MSGLangEN])
+	assert.NotNil(t, report.Err())
+
+	// 错误码未以状态码开头仅为警告；语言经规范化后比较
+	catalog, err = ParseCatalog([]byte(`{"languages":["en-US","zh"],"messages":[
+		{"code":10001,"status":400,"text":{"en_us":"a","ZH":"甲"}},
+		{"code":10002,"status":404,"text":{"EN-us":"b"}}
+	]}`), CatalogJSON)
+	assert.Nil(t, err)
+
+	report = catalog.Validate()
+	assert.Nil(t, report.Err())
+	assert.Len(t, report.Warnings, 2)
+	assert.Empty(t, report.Missing["en-US"])
+	assert.Equal(t, []int{10002}, report.Missing[MSGLangZH])
+	assert.Equal(t, "a", catalog.BuildMessages()[0].I18nMessage("en-US"))
+}
+
+func TestLoadCatalogFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var files []string
+	for _, file := range []string{"orders.json", "users.yaml", "stock.toml"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "catalog", file))
+		assert.Nil(t, err)
+
+		files = append(files, filepath.Join(dir, file))
+		assert.Nil(t, ioutil.WriteFile(files[len(files)-1], data, 0644))
+	}
+
+	report, err := LoadCatalogFiles(files...)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, report.Entries)
+	assert.Equal(t, []int{409201}, report.Missing[MSGLangZH])
+
+	m := Messages.Get(404201)
+	assert.NotNil(t, m)
+	assert.Equal(t, http.StatusNotFound, m.Status())
+	assert.Equal(t, "订单 7 不存在", m.WithArgs(map[string]interface{}{"id": 7}).I18nMessage(MSGLangZH))
+	assert.Equal(t, "2 users are disabled", Messages.Get(400202).WithArgs(map[string]interface{}{"count": 2}).En())
+	assert.Equal(t, "sku A1 is out of stock", Messages.Get(422201).WithArgs(map[string]interface{}{"sku": "A1"}).En())
+
+	// 重新加载，移除的错误码不再存在
+	assert.Nil(t, ioutil.WriteFile(files[0], []byte(`{"messages":[{"code":404201,"status":404,"text":{"en":"order missing"}}]}`), 0644))
+	_, err = ReloadCatalog()
+	assert.Nil(t, err)
+	assert.Equal(t, "order missing", Messages.Get(404201).En())
+	assert.Nil(t, Messages.Get(409201))
+
+	// 无效的目录不会替换当前目录
+	assert.Nil(t, ioutil.WriteFile(files[0], []byte(`{"messages":[{"code":404201,"status":600,"text":{"en":"bad"}}]}`), 0644))
+	_, err = ReloadCatalog()
+	assert.NotNil(t, err)
+	assert.Equal(t, "order missing", Messages.Get(404201).En())
+
+	// 不允许覆盖通过 Messages.Add 注册的错误码
+	Messages.Add(NewErrCodeMessage(http.StatusConflict, 409202, "registered in code"))
+	assert.Nil(t, ioutil.WriteFile(files[0], []byte(`{"messages":[{"code":409202,"status":409,"text":{"en":"from catalog"}}]}`), 0644))
+	report, err = ReloadCatalog()
+	assert.NotNil(t, err)
+	assert.Len(t, report.Duplicates, 1)
+	assert.Equal(t, "registered in code", Messages.Get(409202).En())
+}
+
+func TestParseLang(t *testing.T) {
+	assert.Equal(t, MSGType("zh-TW"), ParseLang("zh_tw"))
+	assert.Equal(t, MSGType("zh-Hant-TW"), ParseLang("ZH-hant-tw"))
+	assert.Equal(t, MSGType("en"), ParseLang(" EN "))
+	assert.Equal(t, MSGType("es-419"), ParseLang("es-419"))
+	assert.Equal(t, MSGType(""), ParseLang(""))
+}
+
+func TestLangFallback(t *testing.T) {
+	assert.Equal(t, []MSGType{"zh-Hant-TW", "zh-Hant", "zh", "en"}, LangFallback("zh-hant-tw"))
+	assert.Equal(t, []MSGType{"en-US", "en"}, LangFallback("en-US"))
+
+	SetDefaultLang(MSGLangZH)
+	defer SetDefaultLang(MSGLangEN)
+
+	assert.Equal(t, []MSGType{"fr-CA", "fr", "zh", "en"}, LangFallback("fr-CA"))
+}
+
+func Test_message_Localize(t *testing.T) {
+	m := NewErrCodeMessage(http.StatusNotFound, 404301, "address not found").
+		AddI18Message(MSGLangZH, "地址不存在").
+		AddI18Message("zh-hant", "地址不存在（繁）")
+
+	assert.Equal(t, "地址不存在（繁）", m.Text("zh-Hant-TW"))
+	assert.Equal(t, "地址不存在", m.Text("zh-CN"))
+	assert.Equal(t, "address not found", m.Text("fr"))
+
+	ml := m.Localize("zh_tw")
+	assert.Equal(t, MSGType("zh-TW"), ml.Lang())
+	assert.Equal(t, "地址不存在", ml.Default())
+	assert.Equal(t, "address not found", m.Default()) // 原消息不受影响
+	assert.Equal(t, "地址不存在", ml.Copy().Default())
+}
+
+func TestMessagesExport(t *testing.T) {
+	Messages.Add(NewErrCodeMessage(http.StatusTeapot, 418501, "short | stout").AddI18Message(MSGLangZH, "矮胖"))
+	Messages.Add(NewCallbackMessage(http.StatusTeapot, 418502, "", func(w http.ResponseWriter, r *http.Request, m message.Message, respData interface{}) {}).
+		AddI18PluralMessage(MSGLangEN, PluralOne, "{count} cup").
+		AddI18PluralMessage(MSGLangEN, PluralOther, "{count} cups"))
+
+	entries := Messages.Export()
+	for i := 1; i < len(entries); i++ {
+		assert.True(t, entries[i-1].Code < entries[i].Code)
+	}
+
+	var teapots []ExportEntry
+	for _, entry := range entries {
+		if entry.Code == 418501 || entry.Code == 418502 {
+			teapots = append(teapots, entry)
+		}
+	}
+
+	assert.Len(t, teapots, 2)
+	assert.Equal(t, map[MSGType]string{MSGLangEN: "short | stout", MSGLangZH: "矮胖"}, teapots[0].Text)
+	assert.False(t, teapots[0].Callback)
+	assert.True(t, teapots[1].Callback)
+	assert.Equal(t, "{count} cups", teapots[1].Plurals[MSGLangEN][PluralOther])
+
+	// JSON
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteMessagesJSON(buf, teapots))
+
+	var decoded []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, float64(418501), decoded[0]["code"])
+	assert.Equal(t, float64(http.StatusTeapot), decoded[0]["status"])
+	assert.Equal(t, true, decoded[1]["callback"])
+
+	// Markdown
+	buf.Reset()
+	assert.Nil(t, WriteMessagesMarkdown(buf, teapots))
+	assert.Equal(t, "| Code | Status | en | zh | Callback |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| 418501 | 418 I'm a teapot | short \\| stout | 矮胖 | no |\n"+
+		"| 418502 | 418 I'm a teapot | one: {count} cup<br>other: {count} cups |  | yes |\n", buf.String())
+}
@@ -0,0 +1,57 @@
+package mhttp
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/message"
+)
+
+// 错误码导出格式
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatMarkdown = "markdown"
+)
+
+// ExportFormatQueryKey 用于指定错误码导出格式的 query 参数名
+const ExportFormatQueryKey = "format"
+
+// ErrorCodesHandler 导出 message.Messages 中的所有错误码，包括状态码、各语言的消息及是否注册了处理回调
+//
+// 默认以 JSON 格式响应，query 参数 format 为 markdown（或 md）或请求头 Accept 包含 text/markdown 时以 Markdown 表格响应
+func ErrorCodesHandler(w http.ResponseWriter, r *http.Request) {
+	entries := message.Messages.Export()
+
+	if exportFormat(r) != ExportFormatMarkdown {
+		JSON(w, r, entries, http.StatusOK)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := message.WriteMarkdown(buf, entries); err != nil {
+		InternalServerError(w, r)
+		return
+	}
+
+	Abort(r)
+	header.SetResponseHeader(w, header.ContentType, header.ContentTypeMarkdown+"; charset=utf-8")
+	writeStatus(w, http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func exportFormat(r *http.Request) string {
+	switch strings.ToLower(r.URL.Query().Get(ExportFormatQueryKey)) {
+	case ExportFormatMarkdown, "md":
+		return ExportFormatMarkdown
+	case ExportFormatJSON:
+		return ExportFormatJSON
+	}
+
+	if strings.Contains(header.GetHeader(r, header.Accept), header.ContentTypeMarkdown) {
+		return ExportFormatMarkdown
+	}
+
+	return ExportFormatJSON
+}
@@ -0,0 +1,158 @@
+package mhttp
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tangzixiang/mplus/context"
+	"github.com/tangzixiang/mplus/header"
+)
+
+const (
+	weakETagPrefix          = "W/"
+	preconditionRequiredKey = "__precondition_required"
+)
+
+// FormatETag 格式化 ETag，tag 未携带双引号时自动补全，weak 为 true 时生成弱校验 ETag
+func FormatETag(tag string, weak bool) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), weakETagPrefix)
+
+	if !strings.HasPrefix(tag, `"`) {
+		tag = `"` + tag + `"`
+	}
+
+	if weak {
+		return weakETagPrefix + tag
+	}
+
+	return tag
+}
+
+// ParseETags 解析 If-Match 及 If-None-Match 请求头中的 ETag 列表
+func ParseETags(value string) []string {
+	var etags []string
+
+	for _, etag := range strings.Split(value, header.SplitSepComma) {
+		if etag = strings.TrimSpace(etag); etag != "" {
+			etags = append(etags, etag)
+		}
+	}
+
+	return etags
+}
+
+// ETagStrongMatch 强比较两个 ETag，任意一方为弱校验 ETag 时不匹配，see RFC 7232 section 2.3.2
+func ETagStrongMatch(a, b string) bool {
+	return a != "" && a == b && !strings.HasPrefix(a, weakETagPrefix)
+}
+
+// ETagWeakMatch 弱比较两个 ETag，忽略弱校验标识，see RFC 7232 section 2.3.2
+func ETagWeakMatch(a, b string) bool {
+	a, b = strings.TrimPrefix(a, weakETagPrefix), strings.TrimPrefix(b, weakETagPrefix)
+	return a != "" && a == b
+}
+
+// CheckNotModified 判断 GET/HEAD 请求携带的 If-None-Match 或 If-Modified-Since 条件是否命中，命中时应响应 304
+//
+// etag 及 modTime 为当前资源的版本信息，为空值时忽略对应条件，If-None-Match 存在时忽略 If-Modified-Since，see RFC 7232 section 6
+func CheckNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := header.GetHeader(r, header.IfNoneMatch); inm != "" {
+		if etag == "" {
+			return false
+		}
+
+		for _, tag := range ParseETags(inm) {
+			if tag == "*" || ETagWeakMatch(tag, etag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return !isModifiedSince(header.GetHeader(r, header.IfModifiedSince), modTime)
+}
+
+// isModifiedSince 判断资源在 since 之后是否修改过，since 为空或无法解析时视为已修改
+func isModifiedSince(since string, modTime time.Time) bool {
+	if since == "" || modTime.IsZero() || modTime.Equal(time.Unix(0, 0)) {
+		return true
+	}
+
+	t, err := http.ParseTime(since)
+	if err != nil {
+		return true
+	}
+
+	// http 时间格式精度为秒
+	return modTime.Truncate(time.Second).After(t)
+}
+
+// ResponseLastModified 获取响应头中的 Last-Modified，未设置或无法解析时返回零值
+func ResponseLastModified(w http.ResponseWriter) time.Time {
+	t, err := http.ParseTime(header.GetResponseHeader(w, header.LastModified))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// CheckPrecondition 判断写请求携带的 If-Match 或 If-Unmodified-Since 条件是否满足，see RFC 7232 section 3.1, 3.4 and RFC 6585 section 3
+//
+// etag 及 modTime 为当前资源的版本信息，If-Match 存在时忽略 If-Unmodified-Since，If-Match 使用强比较；
+// 条件满足返回 200，条件不满足返回 412，required 为 true 且写请求未携带任何条件时返回 428
+func CheckPrecondition(r *http.Request, etag string, modTime time.Time, required bool) int {
+	if im := header.GetHeader(r, header.IfMatch); im != "" {
+		for _, tag := range ParseETags(im) {
+			if (tag == "*" && etag != "") || ETagStrongMatch(tag, etag) {
+				return http.StatusOK
+			}
+		}
+
+		return http.StatusPreconditionFailed
+	}
+
+	if ius := header.GetHeader(r, header.IfUnmodifiedSince); ius != "" {
+		t, err := http.ParseTime(ius)
+		if err != nil || modTime.IsZero() { // 无法解析的时间或未知修改时间，忽略当前条件
+			return http.StatusOK
+		}
+
+		if modTime.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+
+		return http.StatusOK
+	}
+
+	if required && !isSafeMethod(r.Method) {
+		return http.StatusPreconditionRequired
+	}
+
+	return http.StatusOK
+}
+
+// RequirePrecondition 标识当前请求的写操作必须携带 If-Match 或 If-Unmodified-Since
+func RequirePrecondition(r *http.Request) *http.Request {
+	context.SetContextValue(r.Context(), preconditionRequiredKey, true)
+	return r
+}
+
+// IsPreconditionRequired 判断当前请求的写操作是否必须携带 If-Match 或 If-Unmodified-Since
+func IsPreconditionRequired(r *http.Request) bool {
+	return context.GetContextValueBool(r.Context(), preconditionRequiredKey)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
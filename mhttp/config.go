@@ -0,0 +1,256 @@
+package mhttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/tangzixiang/mplus/message"
+)
+
+type configKey struct{}
+
+// Config mhttp 的实例级配置，通过 WithConfig 注入请求上下文后当前请求将使用该配置，未注入时使用 DefaultConfig
+type Config struct {
+	lock              sync.RWMutex
+	defaultLang       message.MSGType
+	defaultMemory     int64
+	maxDecompressSize int64
+	envelope          EnvelopeFunc
+	problemDetails    bool
+	problemType       ProblemTypeFunc
+	langQueryKey      string
+	langCookieName    string
+	supportedLangs    []message.MSGType
+	statusMethods     *StatusMethodHub
+}
+
+// DefaultConfig 默认配置，包级别的 SetDefaultMemorySize、SetEnvelope、SetProblemDetails、RegisterHttpStatusMethod 等函数均作用于该配置
+var DefaultConfig = NewConfig()
+
+// NewConfig 获取一个新的配置实例
+func NewConfig() *Config {
+	return &Config{
+		defaultMemory:     int64(32 * 1024 * 1024),
+		maxDecompressSize: int64(32 * 1024 * 1024),
+		langQueryKey:      "lang",
+		langCookieName:    "lang",
+		statusMethods:     NewStatusMethodHub(),
+	}
+}
+
+// SetDefaultLang 设置无法确定请求语言时使用的语言，为空时使用 message.SetDefaultLang 设置的项目默认语言
+func (c *Config) SetDefaultLang(lang message.MSGType) {
+	c.lock.Lock()
+	c.defaultLang = message.ParseLang(string(lang))
+	c.lock.Unlock()
+}
+
+// DefaultLang 获取无法确定请求语言时使用的语言，未设置时返回 message.DefaultLang
+func (c *Config) DefaultLang() message.MSGType {
+	c.lock.RLock()
+	lang := c.defaultLang
+	c.lock.RUnlock()
+
+	if lang == "" {
+		return message.DefaultLang()
+	}
+
+	return lang
+}
+
+// SetDefaultMemorySize 设置 http.Request.ParseMultipartForm 参数 maxMemory
+func (c *Config) SetDefaultMemorySize(size int64) {
+	c.lock.Lock()
+	c.defaultMemory = size
+	c.lock.Unlock()
+}
+
+// DefaultMemorySize 获取 http.Request.ParseMultipartForm 参数 maxMemory
+func (c *Config) DefaultMemorySize() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.defaultMemory
+}
+
+// SetMaxDecompressSize 设置携带 Content-Encoding 的请求体压缩的内容及解压后的内容允许的最大字节数
+func (c *Config) SetMaxDecompressSize(size int64) {
+	c.lock.Lock()
+	c.maxDecompressSize = size
+	c.lock.Unlock()
+}
+
+// MaxDecompressSize 获取请求体解压后允许的最大字节数
+func (c *Config) MaxDecompressSize() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.maxDecompressSize
+}
+
+// SetEnvelope 设置响应信封，为 nil 时不对响应数据进行包装，see SetEnvelope
+func (c *Config) SetEnvelope(f EnvelopeFunc) {
+	c.lock.Lock()
+	c.envelope = f
+	c.lock.Unlock()
+}
+
+// Envelope 获取响应信封，未设置时返回 nil
+func (c *Config) Envelope() EnvelopeFunc {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.envelope
+}
+
+// SetProblemDetails 设置是否以 RFC 7807 application/problem+json 格式响应异常信息，see SetProblemDetails
+func (c *Config) SetProblemDetails(enable bool) {
+	c.lock.Lock()
+	c.problemDetails = enable
+	c.lock.Unlock()
+}
+
+// ProblemDetails 判断是否以 application/problem+json 格式响应异常信息
+func (c *Config) ProblemDetails() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.problemDetails
+}
+
+// SetProblemType 设置问题类型 URI 的生成方式，为 nil 时问题类型均为 ProblemTypeBlank
+func (c *Config) SetProblemType(f ProblemTypeFunc) {
+	c.lock.Lock()
+	c.problemType = f
+	c.lock.Unlock()
+}
+
+// ProblemType 获取问题类型 URI 的生成方式
+func (c *Config) ProblemType() ProblemTypeFunc {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.problemType
+}
+
+// SetLangQueryKey 设置用于指定请求语言的 query 参数名，为空时不从 query 中获取
+func (c *Config) SetLangQueryKey(key string) {
+	c.lock.Lock()
+	c.langQueryKey = key
+	c.lock.Unlock()
+}
+
+// LangQueryKey 获取用于指定请求语言的 query 参数名
+func (c *Config) LangQueryKey() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.langQueryKey
+}
+
+// SetLangCookieName 设置用于指定请求语言的 cookie 名，为空时不从 cookie 中获取
+func (c *Config) SetLangCookieName(name string) {
+	c.lock.Lock()
+	c.langCookieName = name
+	c.lock.Unlock()
+}
+
+// LangCookieName 获取用于指定请求语言的 cookie 名
+func (c *Config) LangCookieName() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.langCookieName
+}
+
+// SetSupportedLangs 设置项目支持的语言，协商 Accept-Language 时仅选择支持的语言，未设置时直接使用权重最高的语言
+func (c *Config) SetSupportedLangs(langs ...message.MSGType) {
+	supported := make([]message.MSGType, 0, len(langs))
+	for _, lang := range langs {
+		supported = append(supported, message.ParseLang(string(lang)))
+	}
+
+	c.lock.Lock()
+	c.supportedLangs = supported
+	c.lock.Unlock()
+}
+
+// SupportedLangs 获取项目支持的语言的拷贝
+func (c *Config) SupportedLangs() []message.MSGType {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]message.MSGType(nil), c.supportedLangs...)
+}
+
+// RegisterHttpStatusMethod 注册请求状态回调
+func (c *Config) RegisterHttpStatusMethod(statusCode int, f StatusMethodCallback) {
+	c.statusMethods.Register(statusCode, f)
+}
+
+// RegisterHttpStatusClassMethod 注册状态码类别的请求状态回调，状态码不存在精确匹配的回调时使用
+func (c *Config) RegisterHttpStatusClassMethod(class StatusClass, f StatusMethodCallback) {
+	c.statusMethods.RegisterClass(class, f)
+}
+
+// RegisterHttpStatusFallbackMethod 注册兜底的请求状态回调，4xx/5xx 状态码不存在精确匹配及类别匹配的回调时使用
+func (c *Config) RegisterHttpStatusFallbackMethod(f StatusMethodCallback) {
+	c.statusMethods.RegisterFallback(f)
+}
+
+// StatusMethod 获取状态码对应的请求状态回调，依次匹配精确状态码、状态码类别及兜底回调
+func (c *Config) StatusMethod(statusCode int) (StatusMethodCallback, bool) {
+	return c.statusMethods.Lookup(statusCode)
+}
+
+// WithConfig 将配置注入至 ctx
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf 获取当前请求使用的配置，未注入时返回 DefaultConfig
+func ConfigOf(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+
+	return DefaultConfig
+}
+
+type routeStatusMethodsKey struct{}
+
+// WithStatusMethods 将路由级的请求状态回调注入至 ctx，其优先级高于 Config 中注册的回调
+func WithStatusMethods(ctx context.Context, hub *StatusMethodHub) context.Context {
+	return context.WithValue(ctx, routeStatusMethodsKey{}, hub)
+}
+
+// LookupStatusMethod 获取当前请求指定状态码的回调，依次查找路由级回调及当前请求配置中注册的回调，
+// 每一级均依次匹配精确状态码、状态码类别及兜底回调
+func LookupStatusMethod(r *http.Request, statusCode int) (StatusMethodCallback, bool) {
+	if hub, ok := r.Context().Value(routeStatusMethodsKey{}).(*StatusMethodHub); ok && hub != nil {
+		if f, exists := hub.Lookup(statusCode); exists {
+			return f, true
+		}
+	}
+
+	return ConfigOf(r).StatusMethod(statusCode)
+}
+
+type routePatternKey struct{}
+
+type routeRequirementsKey struct{}
+
+// WithRoutePattern 将路由模式注入至 ctx，如 /orders/{id}，用于访问日志等场景区分路由
+func WithRoutePattern(ctx context.Context, pattern string) context.Context {
+	return context.WithValue(ctx, routePatternKey{}, pattern)
+}
+
+// RoutePattern 获取当前请求的路由模式，未设置时返回空字符串
+func RoutePattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(routePatternKey{}).(string)
+	return pattern
+}
+
+// WithRouteRequirements 将路由声明的角色、权限范围或权限注入至 ctx
+func WithRouteRequirements(ctx context.Context, requirements []string) context.Context {
+	return context.WithValue(ctx, routeRequirementsKey{}, requirements)
+}
+
+// RouteRequirements 获取当前请求的路由声明的角色、权限范围或权限，未声明时返回 nil
+func RouteRequirements(r *http.Request) []string {
+	requirements, _ := r.Context().Value(routeRequirementsKey{}).([]string)
+	return requirements
+}
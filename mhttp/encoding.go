@@ -0,0 +1,143 @@
+package mhttp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tangzixiang/mplus/header"
+)
+
+// 请求体内容编码
+const (
+	EncodingGzip     = "gzip"
+	EncodingXGzip    = "x-gzip"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+var (
+	// ErrContentEncoding 不支持的请求体内容编码
+	ErrContentEncoding = errors.New("content encoding not support")
+	// ErrBodyTooLarge 请求体压缩的内容或解压后的内容超出限制
+	ErrBodyTooLarge = errors.New("decompressed body too large")
+)
+
+// SetMaxDecompressSize 设置 DefaultConfig 中携带 Content-Encoding 的请求体压缩的内容及解压后的内容允许的最大字节数，用于防止 zip bomb，unit is bytes
+func SetMaxDecompressSize(size int64) {
+	DefaultConfig.SetMaxDecompressSize(size)
+}
+
+// MaxDecompressSize 获取 DefaultConfig 中请求体解压后允许的最大字节数
+func MaxDecompressSize() int64 {
+	return DefaultConfig.MaxDecompressSize()
+}
+
+// DecodeRequestBody 根据请求头 Content-Encoding 解压请求体，并将 r.Body 替换为解压后的内容
+//
+// 支持 gzip、deflate 及多重编码（如 "deflate, gzip"），解压成功后会移除 Content-Encoding 请求头，因此重复调用是安全的；
+// 解压失败时 r.Body 会恢复为原始内容，不支持的编码返回 ErrContentEncoding，
+// 压缩的内容或解压后的内容超出当前请求配置的 MaxDecompressSize 返回 ErrBodyTooLarge，读取请求体失败时返回对应的错误
+func DecodeRequestBody(r *http.Request) error {
+	encodings := requestEncodings(r)
+	if len(encodings) == 0 || r.Body == nil {
+		return nil
+	}
+
+	for _, encoding := range encodings {
+		switch encoding {
+		case EncodingGzip, EncodingXGzip, EncodingDeflate:
+		default:
+			return ErrContentEncoding
+		}
+	}
+
+	limit := ConfigOf(r).MaxDecompressSize()
+
+	// 压缩的内容同样受 MaxDecompressSize 限制，超出时保留未读取的部分以便 r.Body 恢复为原始内容
+	raw, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(raw), r.Body))
+		return err
+	}
+
+	if int64(len(raw)) > limit {
+		r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(raw), r.Body))
+		return ErrBodyTooLarge
+	}
+
+	body := raw
+
+	// 多重编码按照编码的逆序解压
+	for i := len(encodings) - 1; i >= 0; i-- {
+		if body, err = decompress(encodings[i], body, limit); err != nil {
+			r.Body = ioutil.NopCloser(bytes.NewBuffer(raw))
+			return err
+		}
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Del(header.ContentEncoding)
+
+	return nil
+}
+
+// requestEncodings 获取请求体的内容编码，identity 会被忽略
+func requestEncodings(r *http.Request) []string {
+	var encodings []string
+
+	for _, value := range header.GetHeaderValues(r, header.ContentEncoding) {
+		for _, encoding := range strings.Split(value, header.SplitSepComma) {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+
+			if encoding == "" || encoding == EncodingIdentity {
+				continue
+			}
+
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+func decompress(encoding string, body []byte, limit int64) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch encoding {
+	case EncodingGzip, EncodingXGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		// RFC 7230 规定 deflate 为 zlib 格式，部分客户端会直接发送 raw deflate 数据
+		if reader, err = zlib.NewReader(bytes.NewReader(body)); err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, ErrContentEncoding
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	decoded, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(decoded)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+
+	return decoded, nil
+}
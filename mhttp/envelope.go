@@ -0,0 +1,83 @@
+package mhttp
+
+import (
+	"net/http"
+
+	"github.com/tangzixiang/mplus/message"
+)
+
+// EnvelopeFunc 响应信封，用于将响应数据包装为统一的结构，m 为当前响应对应的消息，data 为原始响应数据
+type EnvelopeFunc func(r *http.Request, m message.Message, data interface{}) interface{}
+
+// 默认的响应信封字段
+const (
+	EnvelopeCodeKey    = "code"
+	EnvelopeMessageKey = "message"
+	EnvelopeDataKey    = "data"
+)
+
+// DefaultEnvelope 默认的响应信封
+//
+//	{"code":0,"message":"OK","data":{}}
+var DefaultEnvelope = NewEnvelope(EnvelopeCodeKey, EnvelopeMessageKey, EnvelopeDataKey)
+
+// NewEnvelope 根据指定字段名构造响应信封，code 取自 m.ErrCode()，message 取自按请求语言（见 LocalizeMessage）解析后的消息内容，
+// data 为 nil 时使用 m.Data()，两者均为 nil 时使用 EmptyRespData
+func NewEnvelope(codeKey, messageKey, dataKey string) EnvelopeFunc {
+	return func(r *http.Request, m message.Message, data interface{}) interface{} {
+		if data == nil {
+			data = m.Data()
+		}
+
+		if data == nil {
+			data = EmptyRespData
+		}
+
+		return map[string]interface{}{
+			codeKey:    m.ErrCode(),
+			messageKey: LocalizeMessage(r, m).Default(),
+			dataKey:    data,
+		}
+	}
+}
+
+// SetEnvelope 设置 DefaultConfig 的响应信封，设置后 mplus.PP.JSON、mplus.PP.JSONOK、mplus.PP.CallbackByCode 及
+// ValidateErrorHub 的默认处理器均以信封格式响应，设置为 nil 时取消包装
+func SetEnvelope(f EnvelopeFunc) {
+	DefaultConfig.SetEnvelope(f)
+}
+
+// Envelope 获取 DefaultConfig 的响应信封，未设置时返回 nil
+func Envelope() EnvelopeFunc {
+	return DefaultConfig.Envelope()
+}
+
+// JSONEnvelope 以信封格式响应 JSON 数据，状态码取自 m.Status()，当前请求配置未设置响应信封时等效于 JSON
+func JSONEnvelope(w http.ResponseWriter, r *http.Request, m message.Message, data interface{}) {
+	if envelope := ConfigOf(r).Envelope(); envelope != nil {
+		data = envelope(r, LocalizeMessage(r, m), data)
+	}
+
+	JSON(w, r, data, m.Status())
+}
+
+// CallRegisterFuncOrAbortEnvelope 调用已注册的状态回调，状态回调不存在时，
+// 若当前请求配置已设置响应信封且未开启 problem+json 模式则以信封格式响应，否则等效于 CallRegisterFuncOrAbortError
+func CallRegisterFuncOrAbortEnvelope(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
+
+	m = LocalizeMessage(r, m)
+	registeredFunc, exists := LookupStatusMethod(r, statusCode)
+
+	if exists {
+		registeredFunc(w, Abort(r), m, statusCode)
+		return
+	}
+
+	envelope := ConfigOf(r).Envelope()
+	if envelope == nil || IsProblem(r, m) {
+		AbortError(w, r, m)
+		return
+	}
+
+	JSON(w, r, envelope(r, m, nil), statusCode)
+}
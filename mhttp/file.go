@@ -0,0 +1,314 @@
+package mhttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tangzixiang/mplus/header"
+)
+
+const (
+	rangeUnit = "bytes"
+	sniffLen  = 512
+)
+
+var errInvalidRange = errors.New("invalid range")
+
+// httpRange 请求的内容区间
+type httpRange struct {
+	start, length int64
+}
+
+func (hr httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("%s %d-%d/%d", rangeUnit, hr.start, hr.start+hr.length-1, size)
+}
+
+func (hr httpRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		header.ContentRange: {hr.contentRange(size)},
+		header.ContentType:  {contentType},
+	}
+}
+
+// countingWriter 只记录写入字节数的 io.Writer
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// rangesMIMESize 计算以 multipart/byteranges 响应 ranges 时的响应体大小
+func rangesMIMESize(ranges []httpRange, contentType string, size int64) (encSize int64) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	for _, hr := range ranges {
+		_, _ = mw.CreatePart(hr.mimeHeader(contentType, size))
+		encSize += hr.length
+	}
+	_ = mw.Close()
+
+	return encSize + int64(w)
+}
+
+// sumRangesSize 计算 ranges 的总长度
+func sumRangesSize(ranges []httpRange) (size int64) {
+	for _, hr := range ranges {
+		size += hr.length
+	}
+
+	return size
+}
+
+// ServeContent 以流的方式响应 content 的内容，支持 Range 及 If-Range 断点续传
+//
+// 1. 若未设置 Content-Type 则根据 name 的扩展名推断，推断失败时根据内容推断
+//
+// 2. modTime 非零值时设置 Last-Modified，请求携带的 If-None-Match 或 If-Modified-Since 命中时通过 NotModified 响应 304
+//
+// 3. 请求携带 Range 且满足 If-Range 时响应 206，多个区间时以 multipart/byteranges 响应，区间无法满足时通过 RequestedRangeNotSatisfiable 响应 416，
+// 多个区间的总长度超出内容大小时忽略 Range 响应完整内容
+func ServeContent(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, content io.ReadSeeker) {
+	Abort(r)
+
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		InternalServerError(w, r)
+		return
+	}
+
+	if header.GetResponseHeader(w, header.ContentType) == "" {
+		contentType, err := detectContentType(name, content)
+		if err != nil {
+			InternalServerError(w, r)
+			return
+		}
+
+		header.SetResponseHeader(w, header.ContentType, contentType)
+	}
+
+	if !modTime.IsZero() && !modTime.Equal(time.Unix(0, 0)) {
+		header.SetResponseHeader(w, header.LastModified, modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if CheckNotModified(r, header.GetResponseHeader(w, header.Etag), modTime) {
+		w.Header().Del(header.ContentLength)
+		NotModified(w, r)
+		return
+	}
+
+	header.SetResponseHeader(w, header.AcceptRanges, rangeUnit)
+
+	status, sendSize := http.StatusOK, size
+	var sendContent io.Reader = content
+	if rangeValue := header.GetHeader(r, header.Range); rangeValue != "" && checkIfRange(w, r, modTime) {
+		ranges, err := parseRange(rangeValue, size)
+		if err != nil {
+			header.SetResponseHeader(w, header.ContentRange, fmt.Sprintf("%s */%d", rangeUnit, size))
+			RequestedRangeNotSatisfiable(w, r)
+			return
+		}
+
+		switch {
+		case len(ranges) == 1:
+			if _, err := content.Seek(ranges[0].start, io.SeekStart); err != nil {
+				InternalServerError(w, r)
+				return
+			}
+
+			status, sendSize = http.StatusPartialContent, ranges[0].length
+			header.SetResponseHeader(w, header.ContentRange, ranges[0].contentRange(size))
+		case sumRangesSize(ranges) <= size:
+			contentType := header.GetResponseHeader(w, header.ContentType)
+			status, sendSize = http.StatusPartialContent, rangesMIMESize(ranges, contentType, size)
+
+			pr, pw := io.Pipe()
+			defer pr.Close() // 响应未读取完毕时使写入协程退出
+
+			mw := multipart.NewWriter(pw)
+			header.SetResponseHeader(w, header.ContentType, "multipart/byteranges; boundary="+mw.Boundary())
+			sendContent = pr
+
+			go func() {
+				for _, hr := range ranges {
+					part, err := mw.CreatePart(hr.mimeHeader(contentType, size))
+					if err != nil {
+						_ = pw.CloseWithError(err)
+						return
+					}
+
+					if _, err := content.Seek(hr.start, io.SeekStart); err != nil {
+						_ = pw.CloseWithError(err)
+						return
+					}
+
+					if _, err := io.CopyN(part, content, hr.length); err != nil {
+						_ = pw.CloseWithError(err)
+						return
+					}
+				}
+
+				_ = mw.Close()
+				_ = pw.Close()
+			}()
+		}
+	}
+
+	header.SetResponseHeader(w, header.ContentLength, strconv.FormatInt(sendSize, 10))
+	writeStatus(w, status)
+
+	if r.Method != http.MethodHead {
+		_, _ = io.CopyN(w, sendContent, sendSize)
+	}
+}
+
+// Stream 以流的方式响应 reader 的内容，reader 实现了 io.ReadSeeker 时等效于 ServeContent 并支持 Range
+func Stream(w http.ResponseWriter, r *http.Request, reader io.Reader, contentType string) {
+	if contentType != "" {
+		header.SetResponseHeader(w, header.ContentType, contentType)
+	}
+
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		ServeContent(w, r, "", time.Time{}, seeker)
+		return
+	}
+
+	Abort(r)
+
+	header.SetResponseHeaderIf(header.GetResponseHeader(w, header.ContentType) == "", w, header.ContentType, header.ContentTypeStream)
+	writeStatus(w, http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, reader)
+	}
+}
+
+// writeStatus 写出响应状态码，w 为 ResponseWriter 时同时更新其记录的状态码
+func writeStatus(w http.ResponseWriter, statusCode int) {
+	if _, ok := w.(ResponseWriter); ok {
+		SetHTTPRespStatus(w, statusCode)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+}
+
+// detectContentType 根据文件扩展名推断内容类型，推断失败时读取内容进行推断
+func detectContentType(name string, content io.ReadSeeker) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType, nil
+	}
+
+	buf := make([]byte, sniffLen)
+	n, _ := io.ReadFull(content, buf)
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// checkIfRange 判断 If-Range 条件是否满足，不满足时应忽略 Range 响应完整内容，see RFC 7233 section 3.2
+func checkIfRange(w http.ResponseWriter, r *http.Request, modTime time.Time) bool {
+	ifRange := header.GetHeader(r, header.IfRange)
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, weakETagPrefix) {
+		return ETagStrongMatch(ifRange, header.GetResponseHeader(w, header.Etag))
+	}
+
+	t, err := http.ParseTime(ifRange)
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+
+	return modTime.Truncate(time.Second).Equal(t)
+}
+
+// parseRange 解析 Range 请求头，忽略无法满足的区间，所有区间均无法满足时返回异常，see RFC 7233 section 2.1
+func parseRange(value string, size int64) ([]httpRange, error) {
+	if !strings.HasPrefix(value, rangeUnit+"=") {
+		return nil, errInvalidRange
+	}
+
+	var ranges []httpRange
+
+	for _, spec := range strings.Split(strings.TrimPrefix(value, rangeUnit+"="), header.SplitSepComma) {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		i := strings.Index(spec, "-")
+		if i < 0 {
+			return nil, errInvalidRange
+		}
+
+		start, end := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+		var hr httpRange
+		if start == "" {
+			// suffix-byte-range-spec: -n 表示最后 n 个字节
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+
+			if n > size {
+				n = size
+			}
+
+			if n == 0 {
+				continue
+			}
+
+			hr = httpRange{start: size - n, length: n}
+		} else {
+			first, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || first < 0 {
+				return nil, errInvalidRange
+			}
+
+			if first >= size {
+				continue
+			}
+
+			last := size - 1
+			if end != "" {
+				if last, err = strconv.ParseInt(end, 10, 64); err != nil || last < first {
+					return nil, errInvalidRange
+				}
+
+				if last >= size {
+					last = size - 1
+				}
+			}
+
+			hr = httpRange{start: first, length: last - first + 1}
+		}
+
+		ranges = append(ranges, hr)
+	}
+
+	if len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+
+	return ranges, nil
+}
@@ -11,24 +11,22 @@ import (
 )
 
 const (
-	requestAbortKey = "__abort"
-	responseStatus  = "__status"
+	requestAbortKey       = "__abort"
+	requestAbortReasonKey = "__abort_reason"
+	responseStatus        = "__status"
 )
 
-// use for http.Request.ParseMultipartForm, unit is bytes
-var defaultMemory = int64(32 * 1024 * 1024)
-
 // EmptyRespData 空响应体
 var EmptyRespData = map[string]interface{}{}
 
 // SetDefaultMemorySize 设置 http.Request.ParseMultipartForm 参数 maxMemory
 func SetDefaultMemorySize(size int64) {
-	defaultMemory = size
+	DefaultConfig.SetDefaultMemorySize(size)
 }
 
 // DefaultMemorySize 获取 http.Request.ParseMultipartForm 参数 maxMemory
 func DefaultMemorySize() int64 {
-	return defaultMemory
+	return DefaultConfig.DefaultMemorySize()
 }
 
 // Abort 标识当前请求链已中断
@@ -48,6 +46,26 @@ func IsAbort(r *http.Request) bool {
 	return context.GetContextValueBool(r.Context(), requestAbortKey)
 }
 
+// AbortWithReason 标识当前请求链已中断并记录中断原因，可以通过 AbortReason 获取，如用于访问日志
+func AbortWithReason(r *http.Request, reason string) *http.Request {
+	return Abort(SetAbortReason(r, reason))
+}
+
+// SetAbortReason 记录请求链的中断原因，不改变请求链的中断状态，请求链未中断时 AbortReason 不会返回该原因
+func SetAbortReason(r *http.Request, reason string) *http.Request {
+	context.SetContextValue(r.Context(), requestAbortReasonKey, reason)
+	return r
+}
+
+// AbortReason 获取已中断请求链的中断原因，请求链未中断时返回空字符串
+func AbortReason(r *http.Request) string {
+	if !IsAbort(r) {
+		return ""
+	}
+
+	return context.GetContextValueString(r.Context(), requestAbortReasonKey)
+}
+
 // Error 返回异常信息，当前方法触发的请求响应内容将是文本格式
 func Error(w http.ResponseWriter, m message.Message) {
 	http.Error(SetHTTPRespStatus(w, m.Status(), false), m.Default(), m.Status())
@@ -82,26 +100,46 @@ func PlainEmpty(w http.ResponseWriter, m message.Message) {
 	SetHTTPRespStatus(w, m.Status(), true)
 }
 
-// AbortError 终止请求链并返回异常信息，当前方法触发的请求响应内容将是文本格式
+// AbortError 终止请求链并返回异常信息，当前方法触发的请求响应内容将是文本格式，
+// 通过 SetProblemDetails 开启 problem+json 模式后，状态码为 4xx/5xx 时以 application/problem+json 格式响应
 func AbortError(w http.ResponseWriter, r *http.Request, m message.Message) {
+	m = LocalizeMessage(r, m)
+
+	if IsProblem(r, m) {
+		AbortProblemJSON(w, r, m)
+		return
+	}
+
 	Abort(r)
 	Error(w, m)
 }
 
-// AbortEmptyError 终止请求链并返回异常信息，当前方法触发的请求响应内容将是文本格式
+// AbortEmptyError 终止请求链并返回异常信息，当前方法触发的请求响应内容将是文本格式，
+// 通过 SetProblemDetails 开启 problem+json 模式后，状态码为 4xx/5xx 时以 application/problem+json 格式响应
 func AbortEmptyError(w http.ResponseWriter, r *http.Request, m message.Message) {
+	m = LocalizeMessage(r, m)
+
+	if IsProblem(r, m) {
+		AbortProblemJSON(w, r, m)
+		return
+	}
+
 	Abort(r)
 	ErrorEmpty(w, m)
 }
 
 // AbortEmptyPlain 终止请求链，当前方法触发的请求响应内容将是文本格式
 func AbortEmptyPlain(w http.ResponseWriter, r *http.Request, m message.Message) {
+	m = LocalizeMessage(r, m)
+
 	Abort(r)
 	PlainEmpty(w, m)
 }
 
 // AbortPlain 终止请求链，当前方法触发的请求响应内容将是文本格式
 func AbortPlain(w http.ResponseWriter, r *http.Request, m message.Message) {
+	m = LocalizeMessage(r, m)
+
 	Abort(r)
 	Plain(w, m)
 }
@@ -129,7 +167,7 @@ func JSON(w http.ResponseWriter, r *http.Request, data interface{}, status int)
 
 	header.SetResponseHeader(w, "Content-Type", "application/json; charset=utf-8")
 	_, err = SetHTTPRespStatus(w, status).Write(jsonBytes)
-	if err != nil {
+	if err != nil && err != http.ErrBodyNotAllowed && err != http.ErrHandlerTimeout { // 状态码不允许响应体或已超时的写入不视为异常
 		InternalServerError(w, r)
 	}
 }
@@ -154,12 +192,21 @@ func DumpRequest(r *http.Request) string {
 }
 
 // DumpRequestPure 读取 r 的 body 内容并保持 r.Body 可持续使用
-// 一般用于请求 handler 中读取 body 数据后，并保证后续代码可再次通过 r.Body 读取数据
+// 一般用于请求 handler 中读取 body 数据后，并保证后续代码可再次通过 r.Body 读取数据，读取失败时返回已读取的部分，需要读取错误时使用 ReadRequestBody
 func DumpRequestPure(r *http.Request) []byte {
+	body, _ := ReadRequestBody(r)
+	return body
+}
 
-	body, _ := ioutil.ReadAll(r.Body)
+// ReadRequestBody 与 DumpRequestPure 相同，但读取失败时返回对应的错误，此时 r.Body 为已读取的部分
+func ReadRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
 	// Reset resp.Body so it can be use again
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-	return body
+	return body, err
 }
@@ -0,0 +1,154 @@
+package mhttp
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tangzixiang/mplus/context"
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/message"
+)
+
+const requestLangKey = "__lang"
+
+// SetLangQueryKey 设置 DefaultConfig 中用于指定请求语言的 query 参数名，为空时不从 query 中获取
+func SetLangQueryKey(key string) {
+	DefaultConfig.SetLangQueryKey(key)
+}
+
+// SetLangCookieName 设置 DefaultConfig 中用于指定请求语言的 cookie 名，为空时不从 cookie 中获取
+func SetLangCookieName(name string) {
+	DefaultConfig.SetLangCookieName(name)
+}
+
+// SetSupportedLangs 设置 DefaultConfig 中项目支持的语言，协商 Accept-Language 时仅选择支持的语言，未设置时直接使用权重最高的语言
+func SetSupportedLangs(langs ...message.MSGType) {
+	DefaultConfig.SetSupportedLangs(langs...)
+}
+
+// SupportedLangs 获取 DefaultConfig 中项目支持的语言
+func SupportedLangs() []message.MSGType {
+	return DefaultConfig.SupportedLangs()
+}
+
+// SetRequestLang 指定当前请求的语言，优先级高于 query、cookie 及 Accept-Language，可以用于在中间件中根据用户配置等指定语言，
+// 应使用返回的请求继续处理，请求上下文中不存在 mplus 上下文时语言仅记录至返回的请求
+func SetRequestLang(r *http.Request, lang message.MSGType) *http.Request {
+	return r.WithContext(context.SetContextValue(r.Context(), requestLangKey, message.ParseLang(string(lang))))
+}
+
+// RequestLang 获取当前请求的语言，依次通过 SetRequestLang 指定的语言、query 参数、cookie 及 Accept-Language 协商获取，均不存在时返回空字符串
+//
+// 获取的结果会缓存至请求上下文中
+func RequestLang(r *http.Request) message.MSGType {
+	if lang, ok := context.GetContextValue(r.Context(), requestLangKey).(message.MSGType); ok {
+		return lang
+	}
+
+	lang := resolveRequestLang(r)
+	context.SetContextValue(r.Context(), requestLangKey, lang)
+	return lang
+}
+
+func resolveRequestLang(r *http.Request) message.MSGType {
+	config := ConfigOf(r)
+
+	if langQueryKey := config.LangQueryKey(); langQueryKey != "" && r.URL != nil {
+		if lang := r.URL.Query().Get(langQueryKey); lang != "" {
+			return message.ParseLang(lang)
+		}
+	}
+
+	if langCookieName := config.LangCookieName(); langCookieName != "" {
+		if cookie, err := r.Cookie(langCookieName); err == nil && cookie.Value != "" {
+			return message.ParseLang(cookie.Value)
+		}
+	}
+
+	return NegotiateLang(header.GetHeader(r, header.AcceptLanguage), config.SupportedLangs()...)
+}
+
+// NegotiateLang 根据 Accept-Language 协商语言，see RFC 7231 section 5.3.5
+//
+// supported 为空时返回权重最高的语言；否则按权重依次匹配语言自身及其上级语言（如 zh-TW → zh），
+// 通配符 * 匹配第一个支持的语言，均无法匹配时返回空字符串
+func NegotiateLang(acceptLanguage string, supported ...message.MSGType) message.MSGType {
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if len(supported) == 0 {
+			if tag == "*" {
+				continue
+			}
+			return message.ParseLang(tag)
+		}
+
+		if tag == "*" {
+			return supported[0]
+		}
+
+		for _, lang := range message.LangParents(message.MSGType(tag)) {
+			for _, s := range supported {
+				if s == lang {
+					return s
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseAcceptLanguage 解析 Accept-Language 并按权重由高到低排序，忽略权重为 0 的语言
+func parseAcceptLanguage(value string) []string {
+	type weightedTag struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.Split(value, header.SplitSepComma) {
+		segments := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(segments[0])
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range segments[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q > 0 {
+			tags = append(tags, weightedTag{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+
+	return result
+}
+
+// LocalizeMessage 获取当前请求语言的消息拷贝，无法确定请求语言时使用当前请求配置的默认语言，均不存在时返回 m 本身
+func LocalizeMessage(r *http.Request, m message.Message) message.Message {
+	lang := RequestLang(r)
+	if lang == "" {
+		lang = ConfigOf(r).DefaultLang()
+	}
+
+	if lang == "" || lang == m.Lang() {
+		return m
+	}
+
+	return m.Localize(lang)
+}
@@ -0,0 +1,63 @@
+package mhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tangzixiang/mplus/context"
+)
+
+const principalKey = "__principal"
+
+// Principal 认证通过的主体
+type Principal struct {
+	// ID 主体标识，如用户名、用户 ID 或 API key 的所有者
+	ID string
+	// Scheme 认证方式，如 Basic、Bearer、APIKey
+	Scheme string
+	// Scopes 主体拥有的权限范围
+	Scopes []string
+	// Claims 认证凭证中携带的声明，如 JWT 的 claims
+	Claims map[string]interface{}
+	// Data 认证时附带的自定义数据，如用户对象
+	Data interface{}
+}
+
+// HasScope 判断主体是否拥有指定的权限范围
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DecodeClaims 将主体携带的声明映射至 v 指向的结构体，字段通过 json 标签与声明名称对应
+func (p *Principal) DecodeClaims(v interface{}) error {
+	data, err := json.Marshal(p.Claims)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// SetPrincipal 记录当前请求认证通过的主体，应使用返回的请求继续处理，请求上下文中不存在 mplus 上下文时主体仅记录至返回的请求
+func SetPrincipal(r *http.Request, principal *Principal) *http.Request {
+	return r.WithContext(context.SetContextValue(r.Context(), principalKey, principal))
+}
+
+// GetPrincipal 获取当前请求认证通过的主体，未认证时返回 nil
+func GetPrincipal(r *http.Request) *Principal {
+	principal, _ := context.GetContextValue(r.Context(), principalKey).(*Principal)
+	return principal
+}
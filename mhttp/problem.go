@@ -0,0 +1,164 @@
+package mhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tangzixiang/mplus/context"
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/message"
+)
+
+const (
+	fieldErrorsKey = "__field_errors"
+
+	// ProblemTypeBlank 问题类型未指定时的默认值，see RFC 7807 section 4.2
+	ProblemTypeBlank = "about:blank"
+)
+
+// 问题详情的扩展字段
+const (
+	ProblemCodeKey        = "code"
+	ProblemRequestIDKey   = "request_id"
+	ProblemFieldErrorsKey = "errors"
+)
+
+// ProblemTypeFunc 获取问题类型的 URI，返回空字符串时使用 ProblemTypeBlank
+type ProblemTypeFunc func(r *http.Request, m message.Message) string
+
+// SetProblemDetails 设置 DefaultConfig 是否以 RFC 7807 application/problem+json 格式响应异常信息
+//
+// 开启后 mplus.PP.ErrorMsg、mplus.PP.AbortErrorMsg、状态码为 4xx/5xx 的状态方法（如 BadRequest、NotFound）
+// 及 ValidateErrorHub 的默认处理器均以 problem+json 格式响应，已通过 RegisterHttpStatusMethod 注册的状态回调依旧优先执行
+func SetProblemDetails(enable bool) {
+	DefaultConfig.SetProblemDetails(enable)
+}
+
+// ProblemDetails 判断 DefaultConfig 是否以 application/problem+json 格式响应异常信息
+func ProblemDetails() bool {
+	return DefaultConfig.ProblemDetails()
+}
+
+// SetProblemType 设置 DefaultConfig 问题类型 URI 的生成方式，为 nil 时问题类型均为 ProblemTypeBlank
+func SetProblemType(f ProblemTypeFunc) {
+	DefaultConfig.SetProblemType(f)
+}
+
+// FieldError 字段校验异常
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// SetFieldErrors 记录当前请求的字段校验异常，以 problem+json 格式响应时将作为扩展字段输出
+func SetFieldErrors(r *http.Request, fieldErrors []FieldError) *http.Request {
+	context.SetContextValue(r.Context(), fieldErrorsKey, fieldErrors)
+	return r
+}
+
+// GetFieldErrors 获取当前请求的字段校验异常
+func GetFieldErrors(r *http.Request) []FieldError {
+	fieldErrors, _ := context.GetContextValue(r.Context(), fieldErrorsKey).([]FieldError)
+	return fieldErrors
+}
+
+// Problem RFC 7807 问题详情，Extensions 中的字段序列化时与标准字段平级输出
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON 序列化问题详情，标准字段不会被 Extensions 中的同名字段覆盖
+func (p Problem) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, len(p.Extensions)+5)
+
+	for key, value := range p.Extensions {
+		data[key] = value
+	}
+
+	data["type"] = p.Type
+	data["title"] = p.Title
+	data["status"] = p.Status
+
+	if p.Detail != "" {
+		data["detail"] = p.Detail
+	}
+
+	if p.Instance != "" {
+		data["instance"] = p.Instance
+	}
+
+	return json.Marshal(data)
+}
+
+// NewProblem 根据 m 构造问题详情
+//
+// title 为状态码对应的标准描述，detail 取自当前请求语言的 m.Default()，instance 为请求路径；
+// 扩展字段包含 m.ErrCode()（非零时）、请求头中的 request-id 及通过 SetFieldErrors 记录的字段校验异常
+func NewProblem(r *http.Request, m message.Message) Problem {
+	m = LocalizeMessage(r, m)
+
+	p := Problem{
+		Type:       ProblemTypeBlank,
+		Title:      http.StatusText(m.Status()),
+		Status:     m.Status(),
+		Detail:     m.Default(),
+		Instance:   r.URL.Path,
+		Extensions: map[string]interface{}{},
+	}
+
+	if problemType := ConfigOf(r).ProblemType(); problemType != nil {
+		if uri := problemType(r, m); uri != "" {
+			p.Type = uri
+		}
+	}
+
+	if p.Detail == p.Title {
+		p.Detail = ""
+	}
+
+	if m.ErrCode() != 0 {
+		p.Extensions[ProblemCodeKey] = m.ErrCode()
+	}
+
+	if requestID := header.GetHeaderRequestID(r); requestID != "" {
+		p.Extensions[ProblemRequestIDKey] = requestID
+	}
+
+	if fieldErrors := GetFieldErrors(r); len(fieldErrors) != 0 {
+		p.Extensions[ProblemFieldErrorsKey] = fieldErrors
+	}
+
+	return p
+}
+
+// ProblemJSON 以 application/problem+json 格式响应异常信息，状态码取自 m.Status()
+func ProblemJSON(w http.ResponseWriter, r *http.Request, m message.Message) {
+	jsonBytes, err := json.Marshal(NewProblem(r, m))
+	if err != nil {
+		InternalServerError(w, r)
+		return
+	}
+
+	header.SetResponseHeader(w, header.ContentType, header.ContentTypeProblemJSON)
+	header.SetResponseHeader(w, header.ContentTypeOptions, "nosniff")
+	writeStatus(w, m.Status())
+
+	_, _ = w.Write(jsonBytes)
+}
+
+// AbortProblemJSON 终止请求链并以 application/problem+json 格式响应异常信息
+func AbortProblemJSON(w http.ResponseWriter, r *http.Request, m message.Message) {
+	Abort(r)
+	ProblemJSON(w, r, m)
+}
+
+// IsProblem 判断 m 是否应以 problem+json 格式响应，即状态码为 4xx/5xx 且当前请求配置开启了 problem+json 模式
+func IsProblem(r *http.Request, m message.Message) bool {
+	return m.Status() >= http.StatusBadRequest && ConfigOf(r).ProblemDetails()
+}
@@ -10,7 +10,10 @@ const defaultStatus = http.StatusOK
 type responseWrite struct {
 	http.ResponseWriter
 
-	status int
+	status      int
+	wroteStatus int
+	written     bool
+	size        int64
 }
 
 // ResponseWriter 请求响应对象
@@ -38,12 +41,56 @@ func (w *responseWrite) Status() int {
 
 func (w *responseWrite) WriteHead(statusCode int) {
 	w.SetStatus(statusCode)
+	w.WriteHeader(statusCode)
+}
+
+// WriteHeader 写出响应状态，仅设置底层 http.ResponseWriter 的状态，不更新 responseWrite 实例的 status
+func (w *responseWrite) WriteHeader(statusCode int) {
+	w.written = true
+	if w.wroteStatus == 0 && statusCode >= http.StatusOK { // 1xx 为临时响应，不视为最终的响应状态
+		w.wroteStatus = statusCode
+	}
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Write 写出响应体
+func (w *responseWrite) Write(b []byte) (int, error) {
+	w.written = true
+	w.wroteImplicitly()
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// wroteImplicitly 未写出响应状态便写出响应体时，底层 http.ResponseWriter 隐式写出 200
+func (w *responseWrite) wroteImplicitly() {
+	if w.wroteStatus == 0 {
+		w.wroteStatus = http.StatusOK
+	}
+}
+
+// WrittenStatus 获取实际写出至底层 http.ResponseWriter 的响应状态，尚未写出时返回 0
+func (w *responseWrite) WrittenStatus() int {
+	return w.wroteStatus
+}
+
+// Written 判断响应状态或响应体是否已开始写出
+func (w *responseWrite) Written() bool {
+	return w.written
+}
+
+// Size 获取已写出的响应体字节数
+func (w *responseWrite) Size() int64 {
+	return w.size
+}
+
 // ReaderFrom 将指定流写入响应内
 func (w *responseWrite) ReaderFrom(src io.Reader) (n int64, err error) {
-	return w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.written = true
+	w.wroteImplicitly()
+	n, err = w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.size += n
+	return n, err
 }
 
 // NewResponseWrite 包装 http.ResponseWriter 获得额外的方法及数据
@@ -61,6 +108,18 @@ func GetHTTPRespStatus(w http.ResponseWriter) int {
 	return http.StatusOK
 }
 
+// GetWrittenStatus 获取实际写出的响应状态，包括直接调用 w.WriteHeader 或 http.Error 等写出的状态，
+// 尚未写出时返回 GetHTTPRespStatus 的结果；w 应为 responseWrite 实例，无法获取时返回 http.StatusOK
+func GetWrittenStatus(w http.ResponseWriter) int {
+	if rw, ok := w.(interface{ WrittenStatus() int }); ok {
+		if status := rw.WrittenStatus(); status != 0 {
+			return status
+		}
+	}
+
+	return GetHTTPRespStatus(w)
+}
+
 // SetHTTPRespStatus 设置响应状态,
 // w 应为 responseWrite 实例，responseWrite 实例只能获取通过 NewResponseWrite 获取
 // coverSupper 用于决定是否覆盖到底层的 http.ResponseWriter,默认 true
@@ -86,6 +145,26 @@ func SetHTTPRespStatus(w http.ResponseWriter, statusCode int, coverSupper ...boo
 	return w
 }
 
+// ResponseStarted 判断响应是否已开始写出，
+// w 应为 responseWrite 实例，无法判断时返回 false
+func ResponseStarted(w http.ResponseWriter) bool {
+	if rw, ok := w.(interface{ Written() bool }); ok {
+		return rw.Written()
+	}
+
+	return false
+}
+
+// ResponseSize 获取已写出的响应体字节数，
+// w 应为 responseWrite 实例，无法获取时返回 0
+func ResponseSize(w http.ResponseWriter) int64 {
+	if rw, ok := w.(interface{ Size() int64 }); ok {
+		return rw.Size()
+	}
+
+	return 0
+}
+
 // UnWrapResponseWriter 解包 ResponseWriter 获取内部的 http.ResponseWriter
 // 当前方法与 NewResponseWrite 相对应
 func UnWrapResponseWriter(resp http.ResponseWriter) http.ResponseWriter {
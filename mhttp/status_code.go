@@ -2,7 +2,6 @@ package mhttp
 
 import (
 	"net/http"
-	"sync"
 
 	"github.com/tangzixiang/mplus/message"
 )
@@ -300,7 +299,8 @@ func NetworkAuthenticationRequired(w http.ResponseWriter, r *http.Request) {
 // CallRegisterFuncOrAbortEmptyError 调用已注册的状态回调，状态回调不存在则使用默认方式终止请求链
 func CallRegisterFuncOrAbortEmptyError(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
 
-	registeredFunc, exists := httpStatusMethodHub[statusCode]
+	m = LocalizeMessage(r, m)
+	registeredFunc, exists := LookupStatusMethod(r, statusCode)
 
 	if !exists {
 		AbortEmptyError(w, r, m)
@@ -313,7 +313,8 @@ func CallRegisterFuncOrAbortEmptyError(w http.ResponseWriter, r *http.Request, m
 // CallRegisterFuncOrAbortEmptyPlain 调用已注册的状态回调，状态回调不存在则使用默认方式终止请求链
 func CallRegisterFuncOrAbortEmptyPlain(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
 
-	registeredFunc, exists := httpStatusMethodHub[statusCode]
+	m = LocalizeMessage(r, m)
+	registeredFunc, exists := LookupStatusMethod(r, statusCode)
 
 	if !exists {
 		AbortEmptyPlain(w, r, m)
@@ -326,7 +327,8 @@ func CallRegisterFuncOrAbortEmptyPlain(w http.ResponseWriter, r *http.Request, m
 // CallRegisterFuncOrAbortError 调用已注册的状态回调，状态回调不存在则使用默认方式终止请求链，resp body 内容取决于 m 持有的信息
 func CallRegisterFuncOrAbortError(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
 
-	registeredFunc, exists := httpStatusMethodHub[statusCode]
+	m = LocalizeMessage(r, m)
+	registeredFunc, exists := LookupStatusMethod(r, statusCode)
 
 	if !exists {
 		AbortError(w, r, m)
@@ -339,7 +341,8 @@ func CallRegisterFuncOrAbortError(w http.ResponseWriter, r *http.Request, m mess
 // CallRegisterFuncOrAbortPlain 调用已注册的状态回调，状态回调不存在则使用默认方式终止请求链，resp body 内容取决于 m 持有的信息
 func CallRegisterFuncOrAbortPlain(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
 
-	registeredFunc, exists := httpStatusMethodHub[statusCode]
+	m = LocalizeMessage(r, m)
+	registeredFunc, exists := LookupStatusMethod(r, statusCode)
 
 	if !exists {
 		AbortPlain(w, r, m)
@@ -351,14 +354,17 @@ func CallRegisterFuncOrAbortPlain(w http.ResponseWriter, r *http.Request, m mess
 
 type StatusMethodCallback func(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int)
 
-var (
-	httpStatusMethodHubLock sync.Mutex
-	httpStatusMethodHub     = map[int]StatusMethodCallback{}
-)
-
 // RegisterHttpStatusMethod 注册默认的请求状态回调
 func RegisterHttpStatusMethod(statusCode int, f StatusMethodCallback) {
-	httpStatusMethodHubLock.Lock()
-	httpStatusMethodHub[statusCode] = f
-	httpStatusMethodHubLock.Unlock()
+	DefaultConfig.RegisterHttpStatusMethod(statusCode, f)
+}
+
+// RegisterHttpStatusClassMethod 注册默认的状态码类别回调，如 StatusClassClientError 对应所有 4xx 状态码，状态码不存在精确匹配的回调时使用
+func RegisterHttpStatusClassMethod(class StatusClass, f StatusMethodCallback) {
+	DefaultConfig.RegisterHttpStatusClassMethod(class, f)
+}
+
+// RegisterHttpStatusFallbackMethod 注册默认的兜底请求状态回调，状态码不存在精确匹配及类别匹配的回调时使用，为 nil 时取消兜底回调
+func RegisterHttpStatusFallbackMethod(f StatusMethodCallback) {
+	DefaultConfig.RegisterHttpStatusFallbackMethod(f)
 }
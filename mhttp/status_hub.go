@@ -0,0 +1,134 @@
+package mhttp
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/tangzixiang/mplus/message"
+)
+
+// StatusClass 状态码类别，如 StatusClassClientError 代表 4xx
+type StatusClass int
+
+// 状态码类别
+const (
+	StatusClassInformational StatusClass = iota + 1 // 1xx
+	StatusClassSuccess                              // 2xx
+	StatusClassRedirection                          // 3xx
+	StatusClassClientError                          // 4xx
+	StatusClassServerError                          // 5xx
+)
+
+// StatusClassOf 获取状态码所属的类别
+func StatusClassOf(statusCode int) StatusClass {
+	return StatusClass(statusCode / 100)
+}
+
+// StatusMethodHub 请求状态回调集合，查找时依次匹配精确状态码、状态码类别及兜底回调，兜底回调仅匹配 4xx/5xx 状态码
+type StatusMethodHub struct {
+	lock     sync.RWMutex
+	exact    map[int]StatusMethodCallback
+	class    map[StatusClass]StatusMethodCallback
+	fallback StatusMethodCallback
+}
+
+// NewStatusMethodHub 获取一个空的请求状态回调集合
+func NewStatusMethodHub() *StatusMethodHub {
+	return &StatusMethodHub{
+		exact: map[int]StatusMethodCallback{},
+		class: map[StatusClass]StatusMethodCallback{},
+	}
+}
+
+// Register 注册指定状态码的回调
+func (h *StatusMethodHub) Register(statusCode int, f StatusMethodCallback) {
+	h.lock.Lock()
+	h.exact[statusCode] = f
+	h.lock.Unlock()
+}
+
+// RegisterClass 注册指定状态码类别的回调，状态码不存在精确匹配的回调时使用
+func (h *StatusMethodHub) RegisterClass(class StatusClass, f StatusMethodCallback) {
+	h.lock.Lock()
+	h.class[class] = f
+	h.lock.Unlock()
+}
+
+// RegisterFallback 注册兜底回调，4xx/5xx 状态码不存在精确匹配及类别匹配的回调时使用，为 nil 时取消兜底回调，
+// 1xx/2xx/3xx 状态码不使用兜底回调，以免改写成功响应及重定向
+func (h *StatusMethodHub) RegisterFallback(f StatusMethodCallback) {
+	h.lock.Lock()
+	h.fallback = f
+	h.lock.Unlock()
+}
+
+// Lookup 获取状态码对应的回调，依次匹配精确状态码、状态码类别及兜底回调，兜底回调仅用于状态码不小于 400 的情况
+func (h *StatusMethodHub) Lookup(statusCode int) (StatusMethodCallback, bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if f, exists := h.exact[statusCode]; exists {
+		return f, true
+	}
+
+	if f, exists := h.class[StatusClassOf(statusCode)]; exists {
+		return f, true
+	}
+
+	if statusCode < http.StatusBadRequest {
+		return nil, false
+	}
+
+	return h.fallback, h.fallback != nil
+}
+
+// Copy 获取一份当前回调集合的拷贝
+func (h *StatusMethodHub) Copy() *StatusMethodHub {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	hub := NewStatusMethodHub()
+	for statusCode, f := range h.exact {
+		hub.exact[statusCode] = f
+	}
+
+	for class, f := range h.class {
+		hub.class[class] = f
+	}
+
+	hub.fallback = h.fallback
+	return hub
+}
+
+// CallRegisterFuncOrError 调用已注册的状态回调，状态回调不存在则使用 Error 响应，不会终止请求链
+func CallRegisterFuncOrError(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
+	m = LocalizeMessage(r, m)
+	if registeredFunc, exists := LookupStatusMethod(r, statusCode); exists {
+		registeredFunc(w, r, m, statusCode)
+		return
+	}
+
+	Error(w, m)
+}
+
+// CallRegisterFuncOrEmptyError 调用已注册的状态回调，状态回调不存在则使用 ErrorEmpty 响应，不会终止请求链
+func CallRegisterFuncOrEmptyError(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
+	m = LocalizeMessage(r, m)
+	if registeredFunc, exists := LookupStatusMethod(r, statusCode); exists {
+		registeredFunc(w, r, m, statusCode)
+		return
+	}
+
+	ErrorEmpty(w, m)
+}
+
+// CallRegisterFuncOrPlain 调用已注册的状态回调，状态回调不存在则使用 Plain 响应，不会终止请求链
+func CallRegisterFuncOrPlain(w http.ResponseWriter, r *http.Request, m message.Message, statusCode int) {
+	m = LocalizeMessage(r, m)
+	if registeredFunc, exists := LookupStatusMethod(r, statusCode); exists {
+		registeredFunc(w, r, m, statusCode)
+		return
+	}
+
+	Plain(w, m)
+}
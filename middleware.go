@@ -6,6 +6,38 @@ import (
 
 type MiddlewareHandler = middleware.MiddlewareHandler
 type MiddlewareHandlerFunc = middleware.MiddlewareHandlerFunc
+type ErrorHandlerFunc = middleware.ErrorHandlerFunc
+type ErrorLogger = middleware.ErrorLogger
+type RecoverLogger = middleware.RecoverLogger
+type CORSConfig = middleware.CORSConfig
+type AccessLogEntry = middleware.AccessLogEntry
+type AccessLogSink = middleware.AccessLogSink
+type AccessLogConfig = middleware.AccessLogConfig
+type RateLimitResult = middleware.RateLimitResult
+type RateLimiter = middleware.RateLimiter
+type RateLimitStore = middleware.RateLimitStore
+type RateLimitUpdater = middleware.RateLimitUpdater
+type RateLimitKeyFunc = middleware.RateLimitKeyFunc
+type RateLimitConfig = middleware.RateLimitConfig
+type TokenBucket = middleware.TokenBucket
+type TokenBucketState = middleware.TokenBucketState
+type SlidingWindow = middleware.SlidingWindow
+type SlidingWindowState = middleware.SlidingWindowState
+type MemoryStore = middleware.MemoryStore
+type IPSet = middleware.IPSet
+type IPList = middleware.IPList
+type Authenticator = middleware.Authenticator
+type BasicVerifyFunc = middleware.BasicVerifyFunc
+type TokenVerifyFunc = middleware.TokenVerifyFunc
+type JWTKey = middleware.JWTKey
+type JWTKeySet = middleware.JWTKeySet
+type JWTKeys = middleware.JWTKeys
+type JWTClaims = middleware.JWTClaims
+type JWTConfig = middleware.JWTConfig
+type JWK = middleware.JWK
+type JWKS = middleware.JWKS
+type JWKSCache = middleware.JWKSCache
+type AuthorizePolicy = middleware.AuthorizePolicy
 
 var (
 	PreMiddleware              = middleware.Pre
@@ -17,4 +49,71 @@ var (
 	Thunk                      = middleware.Thunk
 	ThunkHandler               = middleware.ThunkHandler
 	Bind                       = middleware.Bind
+	ETagMiddleware             = middleware.ETag
+	HandleError                = middleware.HandleError
+	RenderError                = middleware.RenderError
+	SetErrorLogger             = middleware.SetErrorLogger
+	DefaultErrorLogger         = middleware.DefaultErrorLogger
+	RecoverMiddleware          = middleware.Recover
+	SetRecoverLogger           = middleware.SetRecoverLogger
+	DefaultRecoverLogger       = middleware.DefaultRecoverLogger
+	CORSMiddleware             = middleware.CORS
+	DefaultCORSMethods         = middleware.DefaultCORSMethods
+	AccessLogMiddleware        = middleware.AccessLog
+	JSONLinesSink              = middleware.JSONLinesSink
+	CommonLogSink              = middleware.CommonLogSink
+	SetAccessLogSink           = middleware.SetAccessLogSink
+	RateLimitMiddleware        = middleware.RateLimit
+	NewTokenBucket             = middleware.NewTokenBucket
+	NewSlidingWindow           = middleware.NewSlidingWindow
+	NewMemoryStore             = middleware.NewMemoryStore
+	KeyByIP                    = middleware.KeyByIP
+	KeyByHeader                = middleware.KeyByHeader
+	KeyByAPIKey                = middleware.KeyByAPIKey
+	ErrRateLimitConfig         = middleware.ErrRateLimitConfig
+	ErrRateLimitConflict       = middleware.ErrRateLimitConflict
+	TimeoutMiddleware          = middleware.Timeout
+	IPFilterMiddleware         = middleware.IPFilter
+	NewIPSet                   = middleware.NewIPSet
+	ReadIPSet                  = middleware.ReadIPSet
+	NewIPList                  = middleware.NewIPList
+	ErrWatchInterval           = middleware.ErrWatchInterval
+	AuthMiddleware             = middleware.Authenticate
+	BasicAuth                  = middleware.BasicAuth
+	BearerAuth                 = middleware.BearerAuth
+	APIKeyAuth                 = middleware.APIKeyAuth
+	BasicUsers                 = middleware.BasicUsers
+	BearerToken                = middleware.BearerToken
+	ErrMissingCredentials      = middleware.ErrMissingCredentials
+	ErrInvalidCredentials      = middleware.ErrInvalidCredentials
+	JWTMiddleware              = middleware.JWT
+	JWTAuth                    = middleware.JWTAuth
+	ParseJWT                   = middleware.ParseJWT
+	SignJWT                    = middleware.SignJWT
+	JWTClaimsOf                = middleware.JWTClaimsOf
+	ParseJWKS                  = middleware.ParseJWKS
+	MarshalJWKS                = middleware.MarshalJWKS
+	NewJWKSCache               = middleware.NewJWKSCache
+	NewJWKSFile                = middleware.NewJWKSFile
+	NewJWKSHandler             = middleware.NewJWKSHandler
+	ErrTokenMalformed          = middleware.ErrTokenMalformed
+	ErrTokenAlgorithm          = middleware.ErrTokenAlgorithm
+	ErrTokenKeyNotFound        = middleware.ErrTokenKeyNotFound
+	ErrTokenSignature          = middleware.ErrTokenSignature
+	ErrTokenExpired            = middleware.ErrTokenExpired
+	ErrTokenNotYetValid        = middleware.ErrTokenNotYetValid
+	ErrTokenIssuer             = middleware.ErrTokenIssuer
+	ErrTokenAudience           = middleware.ErrTokenAudience
+	AuthorizeMiddleware        = middleware.Authorize
+	SetAuthorizePolicy         = middleware.SetAuthorizePolicy
+	AllScopesPolicy            = middleware.AllScopesPolicy
+	AnyScopePolicy             = middleware.AnyScopePolicy
+)
+
+const (
+	JWTAlgHS256 = middleware.JWTAlgHS256
+	JWTAlgHS384 = middleware.JWTAlgHS384
+	JWTAlgHS512 = middleware.JWTAlgHS512
+	JWTAlgRS256 = middleware.JWTAlgRS256
+	JWTAlgES256 = middleware.JWTAlgES256
 )
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// AccessLogEntry 访问日志记录
+type AccessLogEntry struct {
+	Time        time.Time     `json:"time"`
+	Method      string        `json:"method"`
+	Path        string        `json:"path"`
+	Route       string        `json:"route,omitempty"`
+	Proto       string        `json:"proto"`
+	Status      int           `json:"status"`
+	Bytes       int64         `json:"bytes"`
+	Latency     time.Duration `json:"latency"`
+	ClientIP    string        `json:"client_ip"`
+	RequestID   string        `json:"request_id,omitempty"`
+	UserAgent   string        `json:"user_agent,omitempty"`
+	AbortReason string        `json:"abort_reason,omitempty"`
+}
+
+// AccessLogSink 访问日志的输出方式
+type AccessLogSink func(entry AccessLogEntry)
+
+// JSONLinesSink 以 JSON Lines 格式将访问日志输出至 w，latency 单位为毫秒
+func JSONLinesSink(w io.Writer) AccessLogSink {
+	var lock sync.Mutex
+
+	return func(entry AccessLogEntry) {
+		type jsonEntry AccessLogEntry
+
+		data, err := json.Marshal(struct {
+			jsonEntry
+			Latency float64 `json:"latency"`
+		}{
+			jsonEntry: jsonEntry(entry),
+			Latency:   float64(entry.Latency) / float64(time.Millisecond),
+		})
+		if err != nil {
+			return
+		}
+
+		lock.Lock()
+		_, _ = w.Write(append(data, '\n'))
+		lock.Unlock()
+	}
+}
+
+// CommonLogSink 以 Common Log Format 将访问日志输出至 w
+//
+//	127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+func CommonLogSink(w io.Writer) AccessLogSink {
+	var lock sync.Mutex
+
+	return func(entry AccessLogEntry) {
+		clientIP := entry.ClientIP
+		if clientIP == "" {
+			clientIP = "-"
+		}
+
+		size := "-"
+		if entry.Bytes > 0 {
+			size = fmt.Sprint(entry.Bytes)
+		}
+
+		line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s\n",
+			clientIP, entry.Time.Format("02/Jan/2006:15:04:05 -0700"), entry.Method, entry.Path, entry.Proto, entry.Status, size)
+
+		lock.Lock()
+		_, _ = io.WriteString(w, line)
+		lock.Unlock()
+	}
+}
+
+// SetAccessLogSink 设置 DefaultConfig 中 AccessLogConfig.Sink 为 nil 时访问日志的输出方式，为 nil 时不记录
+func SetAccessLogSink(sink AccessLogSink) {
+	DefaultConfig.SetAccessLogSink(sink)
+}
+
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	// Sink 访问日志的输出方式，为 nil 时使用当前请求配置的 AccessLogSink，默认以 JSON Lines 格式输出至标准输出
+	Sink AccessLogSink
+	// SampleRate 采样率，取值范围为 (0, 1)，不在该范围内时记录所有请求
+	SampleRate float64
+	// ExcludePaths 不记录的请求路径，如健康检查，以 * 结尾时按前缀匹配
+	ExcludePaths []string
+}
+
+// AccessLog 访问日志中间件，请求处理完成后记录请求方法、路径、路由模式、响应状态、响应体字节数、耗时、客户端 IP、request-id、
+// User-Agent 及中断原因
+//
+// 响应状态通过 mhttp.GetWrittenStatus 获取，即实际写出的状态（包括直接调用 w.WriteHeader 或 http.Error 写出的状态），
+// 响应体字节数通过 mhttp.ResponseSize 获取，w 不为 mhttp.ResponseWriter 时会被自动包装；
+// 路由模式通过 mRote.WithPattern 设置，中断原因通过 mhttp.AbortWithReason 记录
+func AccessLog(config AccessLogConfig) MiddlewareHandlerFunc {
+	sampled := func() bool { return true }
+	if config.SampleRate > 0 && config.SampleRate < 1 {
+		var lock sync.Mutex
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+		sampled = func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return random.Float64() < config.SampleRate
+		}
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if excludePath(config.ExcludePaths, r.URL.Path) || !sampled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, ok := w.(mhttp.ResponseWriter); !ok {
+				w = mhttp.NewResponseWrite(w)
+			}
+
+			start := time.Now()
+			next.ServeHTTP(w, r)
+
+			sink := config.Sink
+			if sink == nil {
+				sink = ConfigOf(r).AccessLogSink()
+			}
+
+			if sink == nil {
+				return
+			}
+
+			sink(AccessLogEntry{
+				Time:        start,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Route:       mhttp.RoutePattern(r),
+				Proto:       r.Proto,
+				Status:      mhttp.GetWrittenStatus(w),
+				Bytes:       mhttp.ResponseSize(w),
+				Latency:     time.Since(start),
+				ClientIP:    header.GetClientIP(r),
+				RequestID:   header.GetHeaderRequestID(r),
+				UserAgent:   r.UserAgent(),
+				AbortReason: mhttp.AbortReason(r),
+			})
+		}
+	}
+}
+
+func excludePath(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// 认证方式
+const (
+	SchemeBasic  = "Basic"
+	SchemeBearer = "Bearer"
+	SchemeAPIKey = "APIKey"
+)
+
+// bearerInvalidTokenDescription Bearer 凭证无效时响应的 error_description，不包含认证失败的详细原因
+const bearerInvalidTokenDescription = "The access token is invalid"
+
+var (
+	// ErrMissingCredentials 请求未携带凭证
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials 请求携带的凭证无效
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator 认证器
+type Authenticator interface {
+	// Authenticate 认证请求，请求未携带当前方式的凭证时返回 ErrMissingCredentials，以便尝试下一种方式
+	Authenticate(r *http.Request) (*mhttp.Principal, error)
+	// Challenge 获取认证失败时 WWW-Authenticate 响应头的质询内容，err 为当前认证器认证失败的原因，
+	// 当前认证器未参与认证（请求未携带其凭证）时为 nil；质询内容会响应给客户端，不应包含 err 的详细信息
+	Challenge(err error) string
+}
+
+// BasicVerifyFunc 校验 Basic 认证的用户名及密码，校验失败时返回的主体为 nil 或返回异常
+type BasicVerifyFunc func(r *http.Request, username, password string) (*mhttp.Principal, error)
+
+// TokenVerifyFunc 校验 Bearer token 或 API key，校验失败时返回的主体为 nil 或返回异常
+type TokenVerifyFunc func(r *http.Request, token string) (*mhttp.Principal, error)
+
+type basicAuth struct {
+	realm  string
+	verify BasicVerifyFunc
+}
+
+// BasicAuth HTTP Basic 认证，see RFC 7617
+func BasicAuth(realm string, verify BasicVerifyFunc) Authenticator {
+	return &basicAuth{realm: realm, verify: verify}
+}
+
+func (a *basicAuth) Authenticate(r *http.Request) (*mhttp.Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrMissingCredentials
+	}
+
+	return verified(SchemeBasic, username)(a.verify(r, username, password))
+}
+
+func (a *basicAuth) Challenge(err error) string {
+	return fmt.Sprintf(`%s realm=%q, charset="UTF-8"`, SchemeBasic, a.realm)
+}
+
+type bearerAuth struct {
+	realm  string
+	verify TokenVerifyFunc
+}
+
+// BearerAuth Bearer token 认证，token 取自 Authorization 请求头，see RFC 6750
+func BearerAuth(realm string, verify TokenVerifyFunc) Authenticator {
+	return &bearerAuth{realm: realm, verify: verify}
+}
+
+func (a *bearerAuth) Authenticate(r *http.Request) (*mhttp.Principal, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	return verified(SchemeBearer, "")(a.verify(r, token))
+}
+
+func (a *bearerAuth) Challenge(err error) string {
+	if err == nil || errors.Cause(err) == ErrMissingCredentials {
+		return fmt.Sprintf(`%s realm=%q`, SchemeBearer, a.realm)
+	}
+
+	return fmt.Sprintf(`%s realm=%q, error="invalid_token", error_description=%q`, SchemeBearer, a.realm, bearerInvalidTokenDescription)
+}
+
+// BearerToken 获取 Authorization 请求头中的 Bearer token，不存在时返回空字符串
+func BearerToken(r *http.Request) string {
+	authorization := header.GetHeader(r, header.Authorization)
+	if len(authorization) > len(SchemeBearer) && strings.EqualFold(authorization[:len(SchemeBearer)], SchemeBearer) &&
+		authorization[len(SchemeBearer)] == ' ' {
+		return strings.TrimSpace(authorization[len(SchemeBearer)+1:])
+	}
+
+	return ""
+}
+
+type apiKeyAuth struct {
+	headerName, queryKey string
+	verify               TokenVerifyFunc
+}
+
+// APIKeyAuth API key 认证，依次从请求头 headerName 及 query 参数 queryKey 中获取 API key，为空时不从对应位置获取
+func APIKeyAuth(headerName, queryKey string, verify TokenVerifyFunc) Authenticator {
+	return &apiKeyAuth{headerName: headerName, queryKey: queryKey, verify: verify}
+}
+
+func (a *apiKeyAuth) Authenticate(r *http.Request) (*mhttp.Principal, error) {
+	key := KeyByAPIKey(a.headerName, a.queryKey)(r)
+	if key == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	return verified(SchemeAPIKey, "")(a.verify(r, key))
+}
+
+func (a *apiKeyAuth) Challenge(err error) string {
+	challenge := SchemeAPIKey
+	if a.headerName != "" {
+		challenge += fmt.Sprintf(` header=%q`, a.headerName)
+	}
+
+	if a.queryKey != "" {
+		if a.headerName != "" {
+			challenge += ","
+		}
+		challenge += fmt.Sprintf(` query=%q`, a.queryKey)
+	}
+
+	return challenge
+}
+
+// verified 处理校验函数的结果，在主体的拷贝上补全认证方式及标识，校验函数返回的主体可以是缓存或共享的实例
+func verified(scheme, id string) func(principal *mhttp.Principal, err error) (*mhttp.Principal, error) {
+	return func(principal *mhttp.Principal, err error) (*mhttp.Principal, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		if principal == nil {
+			return nil, ErrInvalidCredentials
+		}
+
+		p := *principal
+		if p.Scheme == "" {
+			p.Scheme = scheme
+		}
+
+		if p.ID == "" {
+			p.ID = id
+		}
+
+		return &p, nil
+	}
+}
+
+// BasicUsers 根据用户名及密码校验 Basic 认证，密码以常量时间比较
+func BasicUsers(users map[string]string) BasicVerifyFunc {
+	return func(r *http.Request, username, password string) (*mhttp.Principal, error) {
+		expected, exists := users[username]
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(password)) != 1 || !exists {
+			return nil, ErrInvalidCredentials
+		}
+
+		return &mhttp.Principal{ID: username}, nil
+	}
+}
+
+// Authenticate 认证中间件，依次尝试 authenticators，第一个认证通过的主体通过 mhttp.SetPrincipal 记录至请求上下文，
+// 可以通过 mplus.PP.Principal 获取
+//
+// 请求未携带某种方式的凭证时尝试下一种方式；凭证无效或均未携带凭证时，以所有认证方式的质询设置 WWW-Authenticate，
+// 认证失败的原因只传递给产生该失败的认证器，其余认证器的质询不携带错误信息；失败的详细原因通过 mhttp.AbortWithReason 记录，
+// 不会响应给客户端；最后通过 mhttp.Unauthorized 终止请求链，已注册的状态回调依旧生效
+func Authenticate(authenticators ...Authenticator) MiddlewareHandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var (
+				failure error
+				failed  = -1
+			)
+
+			for i, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(r)
+				if err == nil {
+					next.ServeHTTP(w, mhttp.SetPrincipal(r, principal))
+					return
+				}
+
+				if errors.Cause(err) != ErrMissingCredentials {
+					failure, failed = err, i
+					break
+				}
+			}
+
+			for i, authenticator := range authenticators {
+				var err error
+				if i == failed {
+					err = failure
+				}
+
+				header.AddResponseHeader(w, header.WWWAuthenticate, authenticator.Challenge(err))
+			}
+
+			if failure == nil {
+				failure = ErrMissingCredentials
+			}
+
+			mhttp.AbortWithReason(r, failure.Error())
+			mhttp.Unauthorized(w, r)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// AuthorizePolicy 授权策略，判断 principal 是否满足 requirements，未认证时 principal 为 nil
+type AuthorizePolicy func(r *http.Request, principal *mhttp.Principal, requirements []string) bool
+
+// SetAuthorizePolicy 设置 DefaultConfig 中 Authorize 中间件未指定策略时使用的默认授权策略，为 nil 时恢复为 AllScopesPolicy
+func SetAuthorizePolicy(policy AuthorizePolicy) {
+	DefaultConfig.SetAuthorizePolicy(policy)
+}
+
+// AllScopesPolicy 主体需拥有 requirements 中的全部角色、权限范围或权限
+func AllScopesPolicy(r *http.Request, principal *mhttp.Principal, requirements []string) bool {
+	if principal == nil {
+		return false
+	}
+
+	for _, requirement := range requirements {
+		if !principal.HasScope(requirement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AnyScopePolicy 主体需拥有 requirements 中的任意一个角色、权限范围或权限，requirements 为空时只要求已认证
+func AnyScopePolicy(r *http.Request, principal *mhttp.Principal, requirements []string) bool {
+	if principal == nil {
+		return false
+	}
+
+	for _, requirement := range requirements {
+		if principal.HasScope(requirement) {
+			return true
+		}
+	}
+
+	return len(requirements) == 0
+}
+
+// Authorize 授权中间件，通过 policy 判断 mhttp.GetPrincipal 获取的主体是否满足 requirements，
+// 请求未认证（主体为 nil）时通过 mhttp.Unauthorized 终止请求链，不满足时通过 mhttp.Forbidden 终止请求链；
+// policy 为 nil 时使用当前请求配置的默认策略，见 Config.SetAuthorizePolicy；
+// 需在 Authenticate 等认证中间件之后使用，通过 mRote.Require 声明时无需关心顺序
+func Authorize(policy AuthorizePolicy, requirements ...string) MiddlewareHandlerFunc {
+	requirements = append([]string(nil), requirements...)
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			principal := mhttp.GetPrincipal(r)
+			if principal == nil {
+				mhttp.AbortWithReason(r, "unauthorized: authentication required")
+				mhttp.Unauthorized(w, r)
+				return
+			}
+
+			evaluate := policy
+			if evaluate == nil {
+				evaluate = ConfigOf(r).AuthorizePolicy()
+			}
+
+			if evaluate(r, principal, requirements) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mhttp.AbortWithReason(r, "forbidden: require "+strings.Join(requirements, ","))
+			mhttp.Forbidden(w, r)
+		}
+	}
+}
@@ -82,13 +82,15 @@ func dealValidateResultErr(w http.ResponseWriter, r *http.Request, err error) {
 		return
 	}
 
-	// 如果存在全局解析异常处理器则优先派遣至全局
-	if errs.GlobalValidateErrorHandler != nil {
-		errs.GlobalValidateErrorHandler(w, r, cErr)
-		return
-	}
+	dispatchValidateError(w, r, cErr)
+}
+
+// dispatchValidateError 将解析异常派遣至已注册的处理器
+func dispatchValidateError(w http.ResponseWriter, r *http.Request, cErr errs.ValidateError) {
+	mhttp.SetAbortReason(r, cErr.Error())
 
-	if errHandler, exists := errs.ValidateErrorHub[cErr.Type()]; exists {
+	// 路由级处理器优先，其次为全局解析异常处理器
+	if errHandler, exists := errs.LookupValidateErrorHandler(r, cErr.Type()); exists {
 		errHandler(w, r, cErr)
 		return
 	}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+)
+
+type configKey struct{}
+
+// 默认的访问日志输出方式，以 JSON Lines 格式输出至标准输出
+var defaultAccessLogSink = JSONLinesSink(os.Stdout)
+
+// Config middleware 的实例级配置，通过 WithConfig 注入请求上下文后当前请求将使用该配置，未注入时使用 DefaultConfig
+type Config struct {
+	lock          sync.RWMutex
+	recoverLogger RecoverLogger
+	errorLogger   ErrorLogger
+	accessLogSink AccessLogSink
+	policy        AuthorizePolicy
+}
+
+// DefaultConfig 默认配置，包级别的 SetRecoverLogger、SetErrorLogger 等函数均作用于该配置
+var DefaultConfig = NewConfig()
+
+// NewConfig 获取一个新的配置实例，日志均使用默认的记录方式
+func NewConfig() *Config {
+	return &Config{
+		recoverLogger: DefaultRecoverLogger,
+		errorLogger:   DefaultErrorLogger,
+		accessLogSink: defaultAccessLogSink,
+		policy:        AllScopesPolicy,
+	}
+}
+
+// SetRecoverLogger 设置 Recover 中间件记录 panic 的方式，为 nil 时不记录
+func (c *Config) SetRecoverLogger(logger RecoverLogger) {
+	c.lock.Lock()
+	c.recoverLogger = logger
+	c.lock.Unlock()
+}
+
+// RecoverLogger 获取 Recover 中间件记录 panic 的方式
+func (c *Config) RecoverLogger() RecoverLogger {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.recoverLogger
+}
+
+// SetErrorLogger 设置 HandleError 遇到未知异常时的记录方式，为 nil 时不记录
+func (c *Config) SetErrorLogger(logger ErrorLogger) {
+	c.lock.Lock()
+	c.errorLogger = logger
+	c.lock.Unlock()
+}
+
+// ErrorLogger 获取 HandleError 遇到未知异常时的记录方式
+func (c *Config) ErrorLogger() ErrorLogger {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.errorLogger
+}
+
+// SetAccessLogSink 设置 AccessLogConfig.Sink 为 nil 时访问日志的输出方式，为 nil 时不记录
+func (c *Config) SetAccessLogSink(sink AccessLogSink) {
+	c.lock.Lock()
+	c.accessLogSink = sink
+	c.lock.Unlock()
+}
+
+// AccessLogSink 获取 AccessLogConfig.Sink 为 nil 时访问日志的输出方式
+func (c *Config) AccessLogSink() AccessLogSink {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.accessLogSink
+}
+
+// SetAuthorizePolicy 设置 Authorize 中间件未指定策略时使用的默认授权策略，为 nil 时恢复为 AllScopesPolicy
+func (c *Config) SetAuthorizePolicy(policy AuthorizePolicy) {
+	if policy == nil {
+		policy = AllScopesPolicy
+	}
+
+	c.lock.Lock()
+	c.policy = policy
+	c.lock.Unlock()
+}
+
+// AuthorizePolicy 获取 Authorize 中间件未指定策略时使用的默认授权策略
+func (c *Config) AuthorizePolicy() AuthorizePolicy {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.policy
+}
+
+// WithConfig 将配置注入至 ctx
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf 获取当前请求使用的配置，未注入时返回 DefaultConfig
+func ConfigOf(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+
+	return DefaultConfig
+}
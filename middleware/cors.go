@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// DefaultCORSMethods CORSConfig.AllowMethods 为空时允许的请求方法
+var DefaultCORSMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CORSConfig 跨域资源共享配置
+type CORSConfig struct {
+	// AllowOrigins 允许的来源，"*" 允许所有来源，支持以 * 匹配子域名，如 https://*.example.com
+	AllowOrigins []string
+	// AllowOriginFunc 自定义来源校验，AllowOrigins 未匹配时使用
+	AllowOriginFunc func(r *http.Request, origin string) bool
+	// AllowMethods 预检请求允许的请求方法，为空时使用 DefaultCORSMethods
+	AllowMethods []string
+	// AllowHeaders 预检请求允许的请求头，为空时允许预检请求 Access-Control-Request-Headers 中的所有请求头
+	AllowHeaders []string
+	// ExposeHeaders 允许客户端读取的响应头
+	ExposeHeaders []string
+	// AllowCredentials 是否允许携带凭证，允许时 Access-Control-Allow-Origin 不会使用 "*"
+	AllowCredentials bool
+	// MaxAge 预检请求结果的缓存时间，单位为秒，为 0 时不设置
+	MaxAge int
+}
+
+// CORS 跨域资源共享中间件
+//
+// 预检请求（携带 Origin 及 Access-Control-Request-Method 的 OPTIONS 请求）将终止请求链：来源允许时通过 mhttp.NoContent 响应，
+// 否则通过 mhttp.Forbidden 响应；来源不被允许的普通请求不设置跨域响应头，继续执行请求链。
+// 除允许所有来源且不允许携带凭证的情况外，响应均携带 Vary: Origin
+func CORS(config CORSConfig) MiddlewareHandlerFunc {
+	allowAll := false
+	origins := make([]string, 0, len(config.AllowOrigins))
+	for _, origin := range config.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		origins = append(origins, strings.ToLower(origin))
+	}
+
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+
+	// 仅在允许所有来源且不允许携带凭证时响应 "*"，此时响应内容与来源无关
+	wildcard := allowAll && !config.AllowCredentials
+
+	allowed := func(r *http.Request, origin string) bool {
+		if allowAll {
+			return true
+		}
+
+		lower := strings.ToLower(origin)
+		for _, pattern := range origins {
+			if matchOrigin(pattern, lower) {
+				return true
+			}
+		}
+
+		return config.AllowOriginFunc != nil && config.AllowOriginFunc(r, origin)
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			origin := header.GetHeader(r, header.Origin)
+			preflight := r.Method == http.MethodOptions && origin != "" && header.GetHeader(r, header.AccessControlRequestMethod) != ""
+
+			if !wildcard {
+				addVary(w, header.Origin)
+			}
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allowed(r, origin) {
+				if preflight {
+					mhttp.Forbidden(w, r)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wildcard {
+				header.SetResponseHeader(w, header.AccessControlAllowOrigin, "*")
+			} else {
+				header.SetResponseHeader(w, header.AccessControlAllowOrigin, origin)
+			}
+
+			header.SetResponseHeaderIf(config.AllowCredentials, w, header.AccessControlAllowCredentials, "true")
+
+			if !preflight {
+				header.SetResponseHeaderIf(exposeHeaders != "", w, header.AccessControlExposeHeaders, exposeHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			addVary(w, header.AccessControlRequestMethod, header.AccessControlRequestHeaders)
+			header.SetResponseHeader(w, header.AccessControlAllowMethods, allowMethods)
+
+			if allowHeaders != "" {
+				header.SetResponseHeader(w, header.AccessControlAllowHeaders, allowHeaders)
+			} else if requestHeaders := header.GetHeader(r, header.AccessControlRequestHeaders); requestHeaders != "" {
+				header.SetResponseHeader(w, header.AccessControlAllowHeaders, requestHeaders)
+			}
+
+			header.SetResponseHeaderIf(config.MaxAge > 0, w, header.AccessControlMaxAge, strconv.Itoa(config.MaxAge))
+
+			mhttp.NoContent(w, r)
+		}
+	}
+}
+
+// matchOrigin 判断来源是否匹配 pattern，pattern 中的 * 匹配任意非空内容
+func matchOrigin(pattern, origin string) bool {
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return pattern == origin
+	}
+
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// addVary 向 Vary 响应头追加 values，已存在的值不重复添加
+func addVary(w http.ResponseWriter, values ...string) {
+	exists := map[string]bool{}
+	for _, v := range header.GetResponseHeaderValues(w, header.Vary) {
+		for _, item := range strings.Split(v, header.SplitSepComma) {
+			exists[strings.ToLower(strings.TrimSpace(item))] = true
+		}
+	}
+
+	for _, value := range values {
+		if !exists[strings.ToLower(value)] {
+			header.AddResponseHeader(w, header.Vary, value)
+			exists[strings.ToLower(value)] = true
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// bufferWrite 缓存响应状态及响应体的 ResponseWriter，用于在响应写出前对响应内容进行处理
+type bufferWrite struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+var _ mhttp.ResponseWriter = &bufferWrite{}
+
+func newBufferWrite(w http.ResponseWriter) *bufferWrite {
+	return &bufferWrite{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *bufferWrite) SetStatus(status int) {
+	w.status = status
+}
+
+func (w *bufferWrite) Status() int {
+	return w.status
+}
+
+func (w *bufferWrite) WriteHead(statusCode int) {
+	w.status = statusCode
+}
+
+func (w *bufferWrite) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
+
+func (w *bufferWrite) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// flush 将缓存的响应状态及响应体写出至 target
+func (w *bufferWrite) flush(target http.ResponseWriter) {
+	if _, ok := target.(mhttp.ResponseWriter); ok {
+		mhttp.SetHTTPRespStatus(target, w.status)
+	} else {
+		target.WriteHeader(w.status)
+	}
+
+	_, _ = target.Write(w.body.Bytes())
+}
+
+// ETag 缓存 GET/HEAD 请求的响应并计算 ETag，请求携带的 If-None-Match 或 If-Modified-Since 命中时通过 mhttp.NotModified 响应 304
+//
+// 若 handler 已通过 mplus.PP.SetETag 设置 ETag 则直接使用，不再计算响应体的摘要；
+// 若 handler 设置了 Last-Modified 响应头，则同时支持 If-Modified-Since；
+// weak 为 true 时计算得到的是弱校验 ETag；仅处理状态码为 200 的响应
+func ETag(weak bool) MiddlewareHandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := newBufferWrite(w)
+			next.ServeHTTP(bw, r)
+
+			if bw.status != http.StatusOK {
+				bw.flush(w)
+				return
+			}
+
+			etag := header.GetResponseHeader(w, header.Etag)
+			if etag == "" {
+				sum := sha1.Sum(bw.body.Bytes())
+				etag = mhttp.FormatETag(hex.EncodeToString(sum[:]), weak)
+				header.SetResponseHeader(w, header.Etag, etag)
+			}
+
+			if !mhttp.CheckNotModified(r, etag, mhttp.ResponseLastModified(w)) {
+				bw.flush(w)
+				return
+			}
+
+			w.Header().Del(header.ContentLength)
+			mhttp.NotModified(w, r)
+		}
+	}
+}
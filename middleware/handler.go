@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/tangzixiang/mplus/errs"
+	"github.com/tangzixiang/mplus/message"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// ErrorHandlerFunc 返回异常的请求处理器，需要通过 HandleError 转换为 http.HandlerFunc 使用
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorLogger 未知异常的记录方式
+type ErrorLogger func(r *http.Request, err error)
+
+// DefaultErrorLogger 默认通过标准库 log 记录异常
+var DefaultErrorLogger ErrorLogger = func(r *http.Request, err error) {
+	log.Printf("mplus: %s %s handle failed: %+v", r.Method, r.URL.Path, err)
+}
+
+// SetErrorLogger 设置 DefaultConfig 中 HandleError 遇到未知异常时的记录方式，为 nil 时不记录
+func SetErrorLogger(logger ErrorLogger) {
+	DefaultConfig.SetErrorLogger(logger)
+}
+
+// ServeHTTP 实现 http.Handler，等效于 HandleError(h).ServeHTTP(w, r)
+func (h ErrorHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	HandleError(h)(w, r)
+}
+
+// HandleError 将 ErrorHandlerFunc 转换为 http.HandlerFunc，可以直接用于 mRote.HandlerFunc，handler 返回的异常按以下规则转换为响应：
+//
+// 1. 异常链中存在 errs.ValidateError 时，派遣至当前请求配置的全局 ValidateError 异常处理器或对应类型的处理器
+//
+// 2. 异常链中存在 message.Message 时，若其附带回调则以 m.Data() 作为响应数据执行回调，否则调用已注册的状态回调或以默认方式响应
+//
+// 3. 其余异常通过当前请求配置的 ErrorLogger 记录后响应 mhttp.InternalServerError
+//
+// 异常链通过 Unwrap 及 github.com/pkg/errors 的 Cause 展开
+func HandleError(handler ErrorHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := handler(w, r); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+// RenderError 将异常转换为响应，转换规则见 HandleError
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	for e := err; e != nil; e = unwrapError(e) {
+		switch v := e.(type) {
+		case errs.ValidateError:
+			dispatchValidateError(w, r, v)
+			return
+		case message.Message:
+			v = mhttp.LocalizeMessage(r, v)
+
+			if v.HasCallback() {
+				v.Do(w, mhttp.Abort(r), v, v.Data())
+				return
+			}
+
+			mhttp.CallRegisterFuncOrAbortEnvelope(w, r, v, v.Status())
+			return
+		}
+	}
+
+	if errorLogger := ConfigOf(r).ErrorLogger(); errorLogger != nil {
+		errorLogger(r, err)
+	}
+
+	mhttp.InternalServerError(w, r)
+}
+
+// unwrapError 获取异常的下一层，同时支持 Unwrap 及 github.com/pkg/errors 的 Cause
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	}
+
+	return nil
+}
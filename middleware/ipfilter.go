@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// IPFilter 根据客户端 IP 放行或拒绝请求，请求被拒绝时通过 mhttp.Forbidden 终止请求链
+//
+// 客户端 IP 通过 header.GetClientIP 获取：未设置受信任的代理时使用直连地址，忽略 X-Forwarded-For 等可被伪造的请求头，
+// 设置后仅在直连地址为受信任的代理时使用解析出的客户端 IP，详见 header.SetTrustedProxies
+//
+// 客户端 IP 属于 deny 时拒绝；allow 不为 nil 且不为空时，仅放行属于 allow 的客户端 IP；allow 及 deny 均可以为 nil；
+// 无法解析客户端 IP 时，若 allow 不为空则拒绝，否则放行
+func IPFilter(allow, deny *IPList) MiddlewareHandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			restricted := allow != nil && allow.Len() != 0
+			ip := net.ParseIP(header.GetClientIP(r))
+
+			switch {
+			case ip == nil && restricted:
+			case ip != nil && deny != nil && deny.Contains(ip):
+			case ip != nil && restricted && !allow.Contains(ip):
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mhttp.AbortWithReason(r, "ip forbidden")
+			mhttp.Forbidden(w, r)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tangzixiang/mplus/header"
+)
+
+// ipTrieNode 二进制前缀树节点，terminal 表示从根节点至当前节点的前缀为集合中的网段
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	terminal bool
+}
+
+// IPSet 基于前缀树的 IP 网段集合，IPv4 及 IPv6 分别存储，查找的时间复杂度与地址长度相关，与网段数量无关
+type IPSet struct {
+	v4, v6 *ipTrieNode
+	size   int
+}
+
+// NewIPSet 根据 CIDR 或单个 IP 构造 IP 网段集合
+func NewIPSet(cidrs ...string) (*IPSet, error) {
+	set := &IPSet{v4: &ipTrieNode{}, v6: &ipTrieNode{}}
+	for _, cidr := range cidrs {
+		ipNet, err := header.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		set.Add(ipNet)
+	}
+
+	return set, nil
+}
+
+// Add 向集合中添加网段
+func (s *IPSet) Add(ipNet *net.IPNet) {
+	ip, root := s.route(ipNet.IP)
+	if ip == nil {
+		return
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if len(ip) == net.IPv4len && bits == 8*net.IPv6len { // IPv4-mapped IPv6 网段
+		if ones -= 8 * (net.IPv6len - net.IPv4len); ones < 0 {
+			ones = 0
+		}
+	}
+
+	node := root
+	for i := 0; i < ones && !node.terminal; i++ {
+		bit := ip[i/8] >> (7 - uint(i%8)) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &ipTrieNode{}
+		}
+		node = node.children[bit]
+	}
+
+	// 已被更大的网段包含时无需添加，否则丢弃被当前网段包含的子网段
+	if !node.terminal {
+		s.size += 1 - node.count()
+		node.terminal = true
+		node.children = [2]*ipTrieNode{}
+	}
+}
+
+// count 获取当前节点下的网段数量
+func (n *ipTrieNode) count() int {
+	if n == nil {
+		return 0
+	}
+
+	if n.terminal {
+		return 1
+	}
+
+	return n.children[0].count() + n.children[1].count()
+}
+
+// Contains 判断 ip 是否属于集合中的网段
+func (s *IPSet) Contains(ip net.IP) bool {
+	if s == nil {
+		return false
+	}
+
+	ip, node := s.route(ip)
+	if ip == nil {
+		return false
+	}
+
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			return true
+		}
+
+		if i == len(ip)*8 {
+			break
+		}
+		node = node.children[ip[i/8]>>(7-uint(i%8))&1]
+	}
+
+	return false
+}
+
+// Len 获取集合中的网段数量，被其他网段包含的网段不计算在内
+func (s *IPSet) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	return s.size
+}
+
+// route 获取 ip 的规范形式及其所属的前缀树
+func (s *IPSet) route(ip net.IP) (net.IP, *ipTrieNode) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, s.v4
+	}
+
+	if ip16 := ip.To16(); ip16 != nil {
+		return ip16, s.v6
+	}
+
+	return nil, nil
+}
+
+// ReadIPSet 按行读取 CIDR 或单个 IP 构造 IP 网段集合，忽略空行及 # 开头的注释
+func ReadIPSet(reader io.Reader) (*IPSet, error) {
+	var cidrs []string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			cidrs = append(cidrs, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewIPSet(cidrs...)
+}
+
+// IPList 可热替换的 IP 网段集合，读取及替换均为原子操作，可以在处理请求的同时重新加载
+type IPList struct {
+	set atomic.Value
+}
+
+// NewIPList 根据 CIDR 或单个 IP 构造可热替换的 IP 网段集合
+func NewIPList(cidrs ...string) (*IPList, error) {
+	set, err := NewIPSet(cidrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &IPList{}
+	list.Store(set)
+	return list, nil
+}
+
+// Store 替换当前的 IP 网段集合
+func (l *IPList) Store(set *IPSet) {
+	l.set.Store(set)
+}
+
+// Load 获取当前的 IP 网段集合
+func (l *IPList) Load() *IPSet {
+	set, _ := l.set.Load().(*IPSet)
+	return set
+}
+
+// Contains 判断 ip 是否属于当前集合中的网段
+func (l *IPList) Contains(ip net.IP) bool {
+	return l.Load().Contains(ip)
+}
+
+// Len 获取当前集合中的网段数量
+func (l *IPList) Len() int {
+	return l.Load().Len()
+}
+
+// LoadFile 从文件中加载并替换当前集合，文件格式见 ReadIPSet，加载失败时保留当前集合
+func (l *IPList) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	set, err := ReadIPSet(file)
+	if err != nil {
+		return err
+	}
+
+	l.Store(set)
+	return nil
+}
+
+// ErrWatchInterval 检查文件的间隔不大于 0
+var ErrWatchInterval = errors.New("watch interval must be positive")
+
+// WatchFile 加载文件并每隔 interval 检查文件的修改时间，文件变更后重新加载；
+// 重新加载失败时保留当前集合并调用 onError（可以为 nil），返回的 stop 用于停止检查；interval 不大于 0 时返回 ErrWatchInterval
+func (l *IPList) WatchFile(path string, interval time.Duration, onError func(err error)) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, errors.Wrapf(ErrWatchInterval, "watch %s every %s", path, interval)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.LoadFile(path); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime, size := info.ModTime(), info.Size()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err == nil && info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+
+			if err == nil {
+				modTime, size = info.ModTime(), info.Size()
+				err = l.LoadFile(path)
+			}
+
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+
+	var closed int32
+	return func() {
+		if atomic.CompareAndSwapInt32(&closed, 0, 1) {
+			close(done)
+		}
+	}, nil
+}
@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWK JSON Web Key，see RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	// K 对称密钥
+	K string `json:"k,omitempty"`
+	// N、E RSA 公钥
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv、X、Y EC 公钥
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWKS 解析 JWKS 文档，忽略 use 不为 sig 及不支持的密钥
+func ParseJWKS(data []byte) (JWTKeys, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "jwks")
+	}
+
+	keys := make(JWTKeys, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.key()
+		if err != nil {
+			return nil, errors.Wrapf(err, "jwks key %q", jwk.Kid)
+		}
+
+		if key != nil {
+			keys = append(keys, JWTKey{ID: jwk.Kid, Algorithm: jwk.Alg, Key: key})
+		}
+	}
+
+	return keys, nil
+}
+
+// key 获取 JWK 对应的密钥，不支持的密钥类型返回 nil
+func (jwk JWK) key() (interface{}, error) {
+	switch jwk.Kty {
+	case "oct":
+		return jwtEncoding.DecodeString(jwk.K)
+	case "RSA":
+		n, err := jwtEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := jwtEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, nil
+		}
+
+		x, err := jwtEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := jwtEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid ec point")
+		}
+
+		return key, nil
+	}
+
+	return nil, nil
+}
+
+// MarshalJWKS 将密钥编码为 JWKS 文档，私钥只编码其公钥部分，[]byte 编码为对称密钥
+func MarshalJWKS(keys ...JWTKey) ([]byte, error) {
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JWK{Kid: key.ID, Alg: key.Algorithm, Use: "sig"}
+
+		switch k := key.Key.(type) {
+		case *rsa.PrivateKey:
+			key.Key = &k.PublicKey
+		case *ecdsa.PrivateKey:
+			key.Key = &k.PublicKey
+		}
+
+		switch k := key.Key.(type) {
+		case []byte:
+			jwk.Kty, jwk.K = "oct", jwtEncoding.EncodeToString(k)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = jwtEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = jwtEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		case *ecdsa.PublicKey:
+			if k.Curve != elliptic.P256() {
+				return nil, errors.Errorf("jwks key %q: unsupported curve", key.ID)
+			}
+
+			size := (k.Curve.Params().BitSize + 7) / 8
+			jwk.Kty, jwk.Crv = "EC", "P-256"
+			jwk.X = jwtEncoding.EncodeToString(padBytes(k.X.Bytes(), size))
+			jwk.Y = jwtEncoding.EncodeToString(padBytes(k.Y.Bytes(), size))
+		default:
+			return nil, errors.Errorf("jwks key %q: unsupported key type %T", key.ID, key.Key)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return json.Marshal(set)
+}
+
+// JWKSCache 带缓存的 JWKS 密钥集合，缓存超过 TTL 或遇到未知的 kid 时重新加载，
+// 遇到未知 kid 触发的重新加载间隔不小于 MinRefresh，重新加载失败时继续使用当前缓存
+//
+// 加载在锁外进行，同一时刻只有一个加载，其余请求最多等待 LoadTimeout 后共享其结果，加载完成后在锁内替换密钥集合；
+// 因此在加载过程中再次获取密钥（如通过带有 JWT 认证的自身接口加载 JWKS）不会死锁
+type JWKSCache struct {
+	// TTL 缓存时长，为 0 时只在遇到未知 kid 时重新加载
+	TTL time.Duration
+	// MinRefresh 遇到未知 kid 时重新加载的最小间隔
+	MinRefresh time.Duration
+	// LoadTimeout 等待其他请求正在进行的加载的最长时间，为 0 时不等待
+	LoadTimeout time.Duration
+	// Now 获取当前时间，为 nil 时使用 time.Now
+	Now func() time.Time
+
+	load     func() ([]byte, error)
+	lock     sync.Mutex
+	keys     JWTKeys
+	loadedAt time.Time
+	loaded   bool
+	inflight *jwksLoad
+}
+
+// jwksLoad 正在进行的加载
+type jwksLoad struct {
+	done chan struct{}
+	err  error
+}
+
+// errJWKSLoadTimeout 等待其他请求的加载超时
+var errJWKSLoadTimeout = errors.New("jwks load timeout")
+
+// NewJWKSCache 获取一个通过 load 加载 JWKS 文档的密钥集合
+func NewJWKSCache(load func() ([]byte, error), ttl time.Duration) *JWKSCache {
+	return &JWKSCache{TTL: ttl, MinRefresh: time.Second, LoadTimeout: 5 * time.Second, load: load}
+}
+
+// NewJWKSFile 获取一个从本地文件加载 JWKS 文档的密钥集合
+func NewJWKSFile(path string, ttl time.Duration) *JWKSCache {
+	return NewJWKSCache(func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}, ttl)
+}
+
+// NewJWKSHandler 获取一个从 handler 加载 JWKS 文档的密钥集合，handler 在进程内以 GET 请求调用，
+// 适用于由当前服务自身提供 JWKS 的场景
+func NewJWKSHandler(handler http.Handler, ttl time.Duration) *JWKSCache {
+	return NewJWKSCache(func() ([]byte, error) {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		w := &jwksRecorder{header: http.Header{}}
+		handler.ServeHTTP(w, r)
+		if w.status() != http.StatusOK {
+			return nil, errors.Errorf("jwks handler responded %d", w.status())
+		}
+
+		return w.body.Bytes(), nil
+	}, ttl)
+}
+
+// jwksRecorder 记录进程内调用 JWKS handler 的响应
+type jwksRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *jwksRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *jwksRecorder) WriteHeader(statusCode int) {
+	if w.code == 0 {
+		w.code = statusCode
+	}
+}
+
+func (w *jwksRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *jwksRecorder) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+
+	return w.code
+}
+
+// Refresh 立即重新加载密钥集合，加载失败时保留当前缓存
+func (c *JWKSCache) Refresh() error {
+	return c.refresh(nowOf(c.Now))
+}
+
+// refresh 重新加载密钥集合，已有加载正在进行时等待其结果
+func (c *JWKSCache) refresh(now time.Time) error {
+	c.lock.Lock()
+	if call := c.inflight; call != nil {
+		c.lock.Unlock()
+		return c.wait(call)
+	}
+
+	call := &jwksLoad{done: make(chan struct{})}
+	c.inflight = call
+	c.loadedAt = now
+	c.lock.Unlock()
+
+	var keys JWTKeys
+	data, err := c.load()
+	if err == nil {
+		keys, err = ParseJWKS(data)
+	}
+
+	c.lock.Lock()
+	if err == nil {
+		c.keys, c.loaded = keys, true
+	}
+	c.inflight = nil
+	c.lock.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// wait 等待其他请求正在进行的加载，最多等待 LoadTimeout
+func (c *JWKSCache) wait(call *jwksLoad) error {
+	if c.LoadTimeout <= 0 {
+		return errJWKSLoadTimeout
+	}
+
+	timer := time.NewTimer(c.LoadTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-timer.C:
+		return errJWKSLoadTimeout
+	}
+}
+
+// snapshot 获取当前的密钥集合及其加载状态
+func (c *JWKSCache) snapshot() (keys JWTKeys, loadedAt time.Time, loaded bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.keys, c.loadedAt, c.loaded
+}
+
+// Key 实现 JWTKeySet
+func (c *JWKSCache) Key(kid, alg string) (interface{}, error) {
+	now := nowOf(c.Now)
+
+	keys, loadedAt, loaded := c.snapshot()
+	if !loaded || (c.TTL > 0 && now.Sub(loadedAt) >= c.TTL) {
+		if err := c.refresh(now); err != nil && !loaded {
+			return nil, err
+		}
+		keys, loadedAt, _ = c.snapshot()
+	}
+
+	key, err := keys.Key(kid, alg)
+	if err == nil || now.Sub(loadedAt) < c.MinRefresh {
+		return key, err
+	}
+
+	if err := c.refresh(now); err != nil {
+		return nil, ErrTokenKeyNotFound
+	}
+
+	keys, _, _ = c.snapshot()
+	return keys.Key(kid, alg)
+}
@@ -0,0 +1,465 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// 支持的 JWT 签名算法
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgHS384 = "HS384"
+	JWTAlgHS512 = "HS512"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+)
+
+var (
+	// ErrTokenMalformed token 格式错误
+	ErrTokenMalformed = errors.New("token is malformed")
+	// ErrTokenAlgorithm token 使用了不支持或不允许的签名算法
+	ErrTokenAlgorithm = errors.New("token algorithm is not allowed")
+	// ErrTokenKeyNotFound 未找到 token 对应的密钥
+	ErrTokenKeyNotFound = errors.New("token key not found")
+	// ErrTokenSignature token 签名校验失败
+	ErrTokenSignature = errors.New("token signature is invalid")
+	// ErrTokenExpired token 已过期
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenNotYetValid token 尚未生效
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	// ErrTokenIssuer token 的签发者不匹配
+	ErrTokenIssuer = errors.New("token issuer is invalid")
+	// ErrTokenAudience token 的受众不匹配
+	ErrTokenAudience = errors.New("token audience is invalid")
+)
+
+var jwtEncoding = base64.RawURLEncoding
+
+// jwtHashes 各签名算法使用的摘要算法
+var jwtHashes = map[string]crypto.Hash{
+	JWTAlgHS256: crypto.SHA256,
+	JWTAlgHS384: crypto.SHA384,
+	JWTAlgHS512: crypto.SHA512,
+	JWTAlgRS256: crypto.SHA256,
+	JWTAlgES256: crypto.SHA256,
+}
+
+// JWTKey JWT 签名密钥
+//
+// Key 的类型需与算法匹配：HS 系列为 []byte，RS256 为 *rsa.PublicKey 或 *rsa.PrivateKey，
+// ES256 为 P-256 曲线的 *ecdsa.PublicKey 或 *ecdsa.PrivateKey
+type JWTKey struct {
+	// ID 密钥标识，对应 token header 中的 kid
+	ID string
+	// Algorithm 密钥使用的签名算法，为空时根据 Key 的类型匹配
+	Algorithm string
+	Key       interface{}
+}
+
+// JWTKeySet JWT 密钥集合
+type JWTKeySet interface {
+	// Key 根据 token header 中的 kid 及 alg 获取验签的密钥，kid 可能为空
+	Key(kid, alg string) (interface{}, error)
+}
+
+// JWTKeys 静态配置的密钥集合
+type JWTKeys []JWTKey
+
+// Key 实现 JWTKeySet，kid 为空时返回第一个与 alg 匹配的密钥
+func (keys JWTKeys) Key(kid, alg string) (interface{}, error) {
+	for _, key := range keys {
+		if (kid == "" || key.ID == kid) && key.match(alg) {
+			return key.Key, nil
+		}
+	}
+
+	return nil, ErrTokenKeyNotFound
+}
+
+// match 判断密钥是否可以用于 alg 算法
+func (key JWTKey) match(alg string) bool {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return false
+	}
+
+	switch k := key.Key.(type) {
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return alg == JWTAlgRS256
+	case *ecdsa.PublicKey:
+		return alg == JWTAlgES256 && k.Curve == elliptic.P256()
+	case *ecdsa.PrivateKey:
+		return alg == JWTAlgES256 && k.Curve == elliptic.P256()
+	}
+
+	return false
+}
+
+// JWTClaims token 中的声明，数值类型以 json.Number 保存
+type JWTClaims map[string]interface{}
+
+// Subject 获取 sub 声明
+func (c JWTClaims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer 获取 iss 声明
+func (c JWTClaims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Audience 获取 aud 声明，aud 可以为字符串或字符串数组
+func (c JWTClaims) Audience() []string {
+	return claimStrings(c["aud"])
+}
+
+// Scopes 获取 scope（以空格分隔的字符串）或 scp（字符串数组）声明中的权限范围
+func (c JWTClaims) Scopes() []string {
+	if scope, ok := c["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	return claimStrings(c["scp"])
+}
+
+// Time 获取 exp、nbf、iat 等时间声明，声明不存在或不是数值时 ok 为 false
+func (c JWTClaims) Time(name string) (t time.Time, ok bool) {
+	var seconds float64
+	switch v := c[name].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return t, false
+		}
+		seconds = f
+	case float64:
+		seconds = v
+	case int64:
+		seconds = float64(v)
+	case int:
+		seconds = float64(v)
+	default:
+		return t, false
+	}
+
+	return time.Unix(0, int64(seconds*float64(time.Second))), true
+}
+
+// Decode 将声明映射至 v 指向的结构体，字段通过 json 标签与声明名称对应，see mhttp.Principal.DecodeClaims
+func (c JWTClaims) Decode(v interface{}) error {
+	return (&mhttp.Principal{Claims: c}).DecodeClaims(v)
+}
+
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+// JWTConfig JWT 校验配置
+type JWTConfig struct {
+	// Keys 验签的密钥集合，如 JWTKeys 及 NewJWKSFile
+	Keys JWTKeySet
+	// Algorithms 允许的签名算法，为空时允许所有支持的算法
+	Algorithms []string
+	// Issuer 不为空时 iss 声明需与之相等
+	Issuer string
+	// Audience 不为空时 aud 声明需至少包含其中之一
+	Audience []string
+	// ClockSkew 校验 exp、nbf 时允许的时钟偏差
+	ClockSkew time.Duration
+	// RequireExp 是否要求 token 携带 exp 声明
+	RequireExp bool
+	// Realm WWW-Authenticate 质询中的 realm
+	Realm string
+	// Now 获取当前时间，为 nil 时使用 time.Now
+	Now func() time.Time
+}
+
+// ParseJWT 解析并校验 JWS 紧凑格式的 token，校验签名及 exp、nbf、iss、aud 声明，返回 token 中的声明
+func ParseJWT(token string, config JWTConfig) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var head struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &head); err != nil {
+		return nil, err
+	}
+
+	if !config.allow(head.Alg) {
+		return nil, errors.Wrap(ErrTokenAlgorithm, head.Alg)
+	}
+
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if config.Keys == nil {
+		return nil, ErrTokenKeyNotFound
+	}
+
+	key, err := config.Keys.Key(head.Kid, head.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyJWTSignature(head.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := JWTClaims{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	return claims, config.validate(claims)
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := jwtEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return ErrTokenMalformed
+	}
+
+	return nil
+}
+
+// allow 判断是否允许 alg 签名算法
+func (config JWTConfig) allow(alg string) bool {
+	if _, supported := jwtHashes[alg]; !supported {
+		return false
+	}
+
+	if len(config.Algorithms) == 0 {
+		return true
+	}
+
+	for _, allowed := range config.Algorithms {
+		if allowed == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate 校验 exp、nbf、iss、aud 声明
+func (config JWTConfig) validate(claims JWTClaims) error {
+	now := nowOf(config.Now)
+
+	if exp, ok := claims.Time("exp"); ok {
+		if !now.Before(exp.Add(config.ClockSkew)) {
+			return ErrTokenExpired
+		}
+	} else if _, exists := claims["exp"]; exists || config.RequireExp {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := claims.Time("nbf"); ok {
+		if now.Add(config.ClockSkew).Before(nbf) {
+			return ErrTokenNotYetValid
+		}
+	} else if _, exists := claims["nbf"]; exists {
+		return ErrTokenNotYetValid
+	}
+
+	if config.Issuer != "" && claims.Issuer() != config.Issuer {
+		return ErrTokenIssuer
+	}
+
+	if len(config.Audience) == 0 {
+		return nil
+	}
+
+	for _, aud := range claims.Audience() {
+		for _, expected := range config.Audience {
+			if aud == expected {
+				return nil
+			}
+		}
+	}
+
+	return ErrTokenAudience
+}
+
+func verifyJWTSignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	hash := jwtHashes[alg]
+	if !(JWTKey{Key: key}).match(alg) {
+		return errors.Wrap(ErrTokenKeyNotFound, "key type mismatch")
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		mac := hmac.New(hash.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrTokenSignature
+		}
+		return nil
+	}
+
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		key = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key = &k.PublicKey
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, hash, digest, signature) != nil {
+			return ErrTokenSignature
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrTokenSignature
+		}
+
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return ErrTokenSignature
+		}
+	}
+
+	return nil
+}
+
+// SignJWT 以 key 对 claims 签名，生成 JWS 紧凑格式的 token，用于本地签发 token；
+// key.Key 需为 []byte、*rsa.PrivateKey 或 *ecdsa.PrivateKey，key.ID 不为空时写入 header 的 kid
+func SignJWT(claims interface{}, alg string, key JWTKey) (string, error) {
+	hash, supported := jwtHashes[alg]
+	if !supported || !key.match(alg) {
+		return "", errors.Wrap(ErrTokenAlgorithm, alg)
+	}
+
+	head := map[string]string{"alg": alg, "typ": "JWT"}
+	if key.ID != "" {
+		head["kid"] = key.ID
+	}
+
+	headData, err := json.Marshal(head)
+	if err != nil {
+		return "", err
+	}
+
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := jwtEncoding.EncodeToString(headData) + "." + jwtEncoding.EncodeToString(claimsData)
+
+	var signature []byte
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, h.Sum(nil)); err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		r, s, err := ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		if err != nil {
+			return "", err
+		}
+
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = append(padBytes(r.Bytes(), size), padBytes(s.Bytes(), size)...)
+	default:
+		return "", errors.Wrap(ErrTokenKeyNotFound, "signing key required")
+	}
+
+	return signingInput + "." + jwtEncoding.EncodeToString(signature), nil
+}
+
+// padBytes 在 b 前补 0 至 size 字节
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// JWTAuth 基于 JWT 的 Bearer token 认证，token 中的 sub 作为主体标识，scope 或 scp 作为权限范围，
+// 全部声明保存至 Principal.Claims，可以通过 JWTClaimsOf 获取或通过 Principal.DecodeClaims 映射至结构体
+func JWTAuth(config JWTConfig) Authenticator {
+	return BearerAuth(config.Realm, func(r *http.Request, token string) (*mhttp.Principal, error) {
+		claims, err := ParseJWT(token, config)
+		if err != nil {
+			return nil, err
+		}
+
+		return &mhttp.Principal{ID: claims.Subject(), Scopes: claims.Scopes(), Claims: claims}, nil
+	})
+}
+
+// JWT JWT 认证中间件，等同于 Authenticate(JWTAuth(config))
+func JWT(config JWTConfig) MiddlewareHandlerFunc {
+	return Authenticate(JWTAuth(config))
+}
+
+// JWTClaimsOf 获取当前请求认证通过的主体携带的声明，未认证时返回 nil
+func JWTClaimsOf(r *http.Request) JWTClaims {
+	principal := mhttp.GetPrincipal(r)
+	if principal == nil {
+		return nil
+	}
+
+	return principal.Claims
+}
@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// RateLimitResult 限流结果
+type RateLimitResult struct {
+	// Allowed 是否允许当前请求
+	Allowed bool
+	// Limit 限流周期内允许的请求数
+	Limit int
+	// Remaining 限流周期内剩余的请求数
+	Remaining int
+	// Reset 距离额度完全恢复的时间
+	Reset time.Duration
+	// RetryAfter 请求被拒绝时，距离下一次允许请求的时间
+	RetryAfter time.Duration
+}
+
+// RateLimiter 限流器
+type RateLimiter interface {
+	Allow(key string) (RateLimitResult, error)
+}
+
+// TokenBucketState 令牌桶的限流状态
+type TokenBucketState struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+}
+
+// ErrRateLimitConfig 限流器的参数无效，如速率、容量、请求数或窗口时长不大于 0
+var ErrRateLimitConfig = errors.New("invalid rate limit config")
+
+// TokenBucket 令牌桶限流器，每秒补充 Rate 个令牌，最多积累 Burst 个令牌，Rate 及 Burst 均需大于 0
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+	Store RateLimitStore
+	// Now 获取当前时间，为 nil 时使用 time.Now
+	Now func() time.Time
+}
+
+// NewTokenBucket 获取一个令牌桶限流器，store 为 nil 时使用 NewMemoryStore 创建的内存存储，
+// rate 或 burst 不大于 0 时返回 ErrRateLimitConfig
+func NewTokenBucket(rate float64, burst int, store RateLimitStore) (*TokenBucket, error) {
+	if !(rate > 0) || burst <= 0 {
+		return nil, errors.Wrapf(ErrRateLimitConfig, "token bucket rate %v burst %d", rate, burst)
+	}
+
+	if store == nil {
+		store = NewMemoryStore(0)
+	}
+
+	return &TokenBucket{Rate: rate, Burst: burst, Store: store}, nil
+}
+
+// Allow 实现 RateLimiter，Rate 或 Burst 不大于 0 时返回 ErrRateLimitConfig
+func (tb *TokenBucket) Allow(key string) (RateLimitResult, error) {
+	if !(tb.Rate > 0) || tb.Burst <= 0 {
+		return RateLimitResult{}, ErrRateLimitConfig
+	}
+
+	now := nowOf(tb.Now)
+	burst := float64(tb.Burst)
+	ttl := time.Duration(burst / tb.Rate * float64(time.Second))
+
+	var s TokenBucketState
+	return updateRateLimitState(tb.Store, key, now, ttl, &s, func(exists bool) RateLimitResult {
+		result := RateLimitResult{Limit: tb.Burst}
+
+		if !exists {
+			s = TokenBucketState{Tokens: burst, Last: now}
+		}
+
+		if elapsed := now.Sub(s.Last); elapsed > 0 {
+			s.Tokens = math.Min(burst, s.Tokens+elapsed.Seconds()*tb.Rate)
+		}
+		s.Last = now
+
+		if s.Tokens >= 1 {
+			s.Tokens--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = seconds((1 - s.Tokens) / tb.Rate)
+		}
+
+		result.Remaining = int(s.Tokens)
+		result.Reset = seconds((burst - s.Tokens) / tb.Rate)
+		return result
+	})
+}
+
+// SlidingWindowState 滑动窗口的限流状态
+type SlidingWindowState struct {
+	Start    time.Time `json:"start"`
+	Previous int       `json:"previous"`
+	Current  int       `json:"current"`
+}
+
+// SlidingWindow 滑动窗口限流器，任意 Window 时长内最多允许 Limit 个请求，
+// 通过上一个窗口的请求数按比例估算滑动窗口内的请求数，Limit 及 Window 均需大于 0
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+	Store  RateLimitStore
+	// Now 获取当前时间，为 nil 时使用 time.Now
+	Now func() time.Time
+}
+
+// NewSlidingWindow 获取一个滑动窗口限流器，store 为 nil 时使用 NewMemoryStore 创建的内存存储，
+// limit 或 window 不大于 0 时返回 ErrRateLimitConfig
+func NewSlidingWindow(limit int, window time.Duration, store RateLimitStore) (*SlidingWindow, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, errors.Wrapf(ErrRateLimitConfig, "sliding window limit %d window %s", limit, window)
+	}
+
+	if store == nil {
+		store = NewMemoryStore(0)
+	}
+
+	return &SlidingWindow{Limit: limit, Window: window, Store: store}, nil
+}
+
+// Allow 实现 RateLimiter，Limit 或 Window 不大于 0 时返回 ErrRateLimitConfig
+func (sw *SlidingWindow) Allow(key string) (RateLimitResult, error) {
+	if sw.Limit <= 0 || sw.Window <= 0 {
+		return RateLimitResult{}, ErrRateLimitConfig
+	}
+
+	now := nowOf(sw.Now)
+	start := now.Truncate(sw.Window)
+	elapsed := now.Sub(start)
+
+	var s SlidingWindowState
+	return updateRateLimitState(sw.Store, key, now, 2*sw.Window, &s, func(exists bool) RateLimitResult {
+		result := RateLimitResult{Limit: sw.Limit, Reset: sw.Window - elapsed}
+
+		switch {
+		case !exists:
+			s = SlidingWindowState{Start: start}
+		case s.Start.Equal(start):
+		case s.Start.Equal(start.Add(-sw.Window)):
+			s = SlidingWindowState{Start: start, Previous: s.Current}
+		default:
+			s = SlidingWindowState{Start: start}
+		}
+
+		weight := 1 - float64(elapsed)/float64(sw.Window)
+		count := float64(s.Previous)*weight + float64(s.Current)
+
+		if count+1 <= float64(sw.Limit) {
+			s.Current++
+			count++
+			result.Allowed = true
+		} else {
+			result.RetryAfter = sw.retryAfter(s, elapsed)
+		}
+
+		result.Remaining = int(math.Max(0, float64(sw.Limit)-math.Ceil(count)))
+		return result
+	})
+}
+
+// retryAfter 估算上一个窗口的请求数按比例衰减至允许下一个请求所需的时间，当前窗口已满时等待至当前窗口结束
+func (sw *SlidingWindow) retryAfter(s SlidingWindowState, elapsed time.Duration) time.Duration {
+	rest := sw.Window - elapsed
+	if s.Previous == 0 || s.Current+1 > sw.Limit {
+		return rest
+	}
+
+	// Previous * (1 - (elapsed+t)/Window) + Current + 1 <= Limit
+	wait := time.Duration(float64(sw.Window)*(1-float64(sw.Limit-s.Current-1)/float64(s.Previous))) - elapsed
+	if wait <= 0 || wait > rest {
+		return rest
+	}
+
+	return wait
+}
+
+func nowOf(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+
+	return time.Now()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimitKeyFunc 获取限流的 key，返回空字符串时不对当前请求限流
+type RateLimitKeyFunc func(r *http.Request) string
+
+// KeyByIP 以客户端 IP 作为限流的 key
+func KeyByIP() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return header.GetClientIP(r)
+	}
+}
+
+// KeyByHeader 以指定请求头作为限流的 key
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		return header.GetHeader(r, name)
+	}
+}
+
+// KeyByAPIKey 以 API key 作为限流的 key，依次从请求头 headerName 及 query 参数 queryKey 中获取，为空时不从对应位置获取
+func KeyByAPIKey(headerName, queryKey string) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if headerName != "" {
+			if key := header.GetHeader(r, headerName); key != "" {
+				return key
+			}
+		}
+
+		if queryKey != "" && r.URL != nil {
+			return r.URL.Query().Get(queryKey)
+		}
+
+		return ""
+	}
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	// Limiter 限流器，如 NewTokenBucket 及 NewSlidingWindow
+	Limiter RateLimiter
+	// KeyFunc 获取限流的 key，为 nil 时使用 KeyByIP
+	KeyFunc RateLimitKeyFunc
+	// Scope 限流 key 的前缀，多个路由或路由组共享同一个存储时用于区分额度
+	Scope string
+}
+
+// rateLimitErrorRetryAfter 限流器返回异常时响应的 Retry-After
+const rateLimitErrorRetryAfter = time.Second
+
+// RateLimit 限流中间件，响应 RateLimit-Limit、RateLimit-Remaining 及 RateLimit-Reset，
+// 请求被拒绝时响应 Retry-After 并通过 mhttp.TooManyRequests 终止请求链，已注册的状态回调依旧生效
+//
+// 限流器返回异常时通过 ErrorLogger 记录并拒绝请求：并发更新冲突（ErrRateLimitConflict）时通过 mhttp.TooManyRequests，
+// 其他异常如外部存储不可用时通过 mhttp.ServiceUnavailable，两者均响应 Retry-After；
+// config.Limiter 为 nil 时 panic
+func RateLimit(config RateLimitConfig) MiddlewareHandlerFunc {
+	if config.Limiter == nil {
+		panic(errors.Wrap(ErrRateLimitConfig, "rate limit limiter is nil"))
+	}
+
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP()
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := config.Limiter.Allow(config.Scope + key)
+			if err != nil {
+				if errorLogger := ConfigOf(r).ErrorLogger(); errorLogger != nil {
+					errorLogger(r, errors.Wrap(err, "rate limit"))
+				}
+
+				header.SetResponseHeader(w, header.RetryAfter, strconv.Itoa(ceilSeconds(rateLimitErrorRetryAfter)))
+				if errors.Cause(err) == ErrRateLimitConflict {
+					mhttp.AbortWithReason(r, "rate limited: state update conflict")
+					mhttp.TooManyRequests(w, r)
+					return
+				}
+
+				mhttp.AbortWithReason(r, "rate limit unavailable")
+				mhttp.ServiceUnavailable(w, r)
+				return
+			}
+
+			header.SetResponseHeaders(w, map[string]string{
+				header.RateLimitLimit:     strconv.Itoa(result.Limit),
+				header.RateLimitRemaining: strconv.Itoa(result.Remaining),
+				header.RateLimitReset:     strconv.Itoa(ceilSeconds(result.Reset)),
+			})
+
+			if !result.Allowed {
+				header.SetResponseHeader(w, header.RetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				mhttp.AbortWithReason(r, "rate limited")
+				mhttp.TooManyRequests(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RateLimitStore 限流状态的存储，可以实现该接口以使用外部存储，如 Redis
+//
+// 状态以 JSON 序列化后的字节存储，限流器通过 Load 及 CompareAndSwap 以乐观锁的方式更新状态，
+// 外部存储可以通过 Lua 脚本、WATCH/MULTI 或 CAS 指令原子地实现 CompareAndSwap；
+// now 为限流器的当前时间，外部存储可以忽略 now 而使用自身的过期机制
+type RateLimitStore interface {
+	// Load 获取 key 对应的状态，不存在或已过期时返回 nil
+	Load(key string, now time.Time) ([]byte, error)
+	// CompareAndSwap 仅当 key 对应的状态与 old 相同时（old 为 nil 表示不存在或已过期）将其替换为 state，
+	// 新状态在 ttl 后过期，返回是否替换成功
+	CompareAndSwap(key string, old, state []byte, now time.Time, ttl time.Duration) (bool, error)
+}
+
+// RateLimitUpdater 可以在锁内原子地读取并更新状态的限流状态存储，限流器优先使用 Update 而非 Load 及 CompareAndSwap，
+// 因此不会出现并发更新冲突；MemoryStore 实现了该接口
+type RateLimitUpdater interface {
+	// Update 在持有 key 对应的锁时以当前状态调用 apply（不存在或已过期时为 nil），并存储 apply 返回的新状态，新状态在 ttl 后过期
+	Update(key string, now time.Time, ttl time.Duration, apply func(old []byte) ([]byte, error)) error
+}
+
+// ErrRateLimitConflict 并发更新限流状态时多次重试后依旧冲突
+var ErrRateLimitConflict = errors.New("rate limit state update conflict")
+
+const rateLimitMaxRetries = 8
+
+// updateRateLimitState 以乐观锁的方式更新 key 对应的限流状态，state 为状态指针，
+// apply 根据状态是否存在更新 state 并返回限流结果，冲突时重新加载状态后重试
+func updateRateLimitState(store RateLimitStore, key string, now time.Time, ttl time.Duration,
+	state interface{}, apply func(exists bool) RateLimitResult) (RateLimitResult, error) {
+
+	if updater, ok := store.(RateLimitUpdater); ok {
+		var result RateLimitResult
+		err := updater.Update(key, now, ttl, func(old []byte) ([]byte, error) {
+			result = apply(old != nil && json.Unmarshal(old, state) == nil)
+			return json.Marshal(state)
+		})
+
+		return result, err
+	}
+
+	for i := 0; i < rateLimitMaxRetries; i++ {
+		old, err := store.Load(key, now)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+
+		// 无法解析的状态视为不存在
+		result := apply(old != nil && json.Unmarshal(old, state) == nil)
+
+		data, err := json.Marshal(state)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+
+		swapped, err := store.CompareAndSwap(key, old, data, now, ttl)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+
+		if swapped {
+			return result, nil
+		}
+	}
+
+	return RateLimitResult{}, ErrRateLimitConflict
+}
+
+const (
+	defaultMemoryStoreShards = 32
+	memoryStoreSweepInterval = 1024 // 每个分片每更新多少次清理一次过期状态
+)
+
+type memoryStoreEntry struct {
+	state  []byte
+	expire time.Time
+}
+
+type memoryStoreShard struct {
+	lock    sync.Mutex
+	entries map[string]memoryStoreEntry
+	updates int
+}
+
+// MemoryStore 分片的内存限流状态存储，过期的状态在访问时或定期清理，过期时间以限流器传入的当前时间计算
+type MemoryStore struct {
+	shards []*memoryStoreShard
+}
+
+var (
+	_ RateLimitStore   = &MemoryStore{}
+	_ RateLimitUpdater = &MemoryStore{}
+)
+
+// NewMemoryStore 获取一个内存限流状态存储，shards 为分片数，小于等于 0 时使用默认分片数
+func NewMemoryStore(shards int) *MemoryStore {
+	if shards <= 0 {
+		shards = defaultMemoryStoreShards
+	}
+
+	store := &MemoryStore{shards: make([]*memoryStoreShard, shards)}
+	for i := range store.shards {
+		store.shards[i] = &memoryStoreShard{entries: map[string]memoryStoreEntry{}}
+	}
+
+	return store
+}
+
+// Load 实现 RateLimitStore
+func (s *MemoryStore) Load(key string, now time.Time) ([]byte, error) {
+	shard := s.shard(key)
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	return shard.load(key, now), nil
+}
+
+// CompareAndSwap 实现 RateLimitStore
+func (s *MemoryStore) CompareAndSwap(key string, old, state []byte, now time.Time, ttl time.Duration) (bool, error) {
+	shard := s.shard(key)
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	current := shard.load(key, now)
+	if (current == nil) != (old == nil) || !bytes.Equal(current, old) {
+		return false, nil
+	}
+
+	shard.store(key, state, now, ttl)
+	return true, nil
+}
+
+// Update 实现 RateLimitUpdater，在持有分片锁时读取并更新状态
+func (s *MemoryStore) Update(key string, now time.Time, ttl time.Duration, apply func(old []byte) ([]byte, error)) error {
+	shard := s.shard(key)
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	state, err := apply(shard.load(key, now))
+	if err != nil {
+		return err
+	}
+
+	shard.store(key, state, now, ttl)
+	return nil
+}
+
+// Len 获取存储中的状态数量，包含尚未清理的过期状态
+func (s *MemoryStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.lock.Lock()
+		n += len(shard.entries)
+		shard.lock.Unlock()
+	}
+
+	return n
+}
+
+func (s *MemoryStore) shard(key string) *memoryStoreShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// load 获取 key 对应的未过期状态，需要在持有分片锁时调用
+func (shard *memoryStoreShard) load(key string, now time.Time) []byte {
+	if entry, exists := shard.entries[key]; exists && now.Before(entry.expire) {
+		return entry.state
+	}
+
+	return nil
+}
+
+// store 存储 key 对应的状态并定期清理过期状态，需要在持有分片锁时调用
+func (shard *memoryStoreShard) store(key string, state []byte, now time.Time, ttl time.Duration) {
+	if shard.updates++; shard.updates%memoryStoreSweepInterval == 0 {
+		for k, entry := range shard.entries {
+			if !now.Before(entry.expire) {
+				delete(shard.entries, k)
+			}
+		}
+	}
+
+	shard.entries[key] = memoryStoreEntry{state: state, expire: now.Add(ttl)}
+}
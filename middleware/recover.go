@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/tangzixiang/mplus/header"
+	"github.com/tangzixiang/mplus/message"
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// RecoverLogger panic 的记录方式，stack 为发生 panic 时的调用栈
+type RecoverLogger func(r *http.Request, err interface{}, stack []byte)
+
+// DefaultRecoverLogger 默认通过标准库 log 记录 panic 及调用栈，日志中包含请求头中的 request-id
+var DefaultRecoverLogger RecoverLogger = func(r *http.Request, err interface{}, stack []byte) {
+	log.Printf("mplus: [%s] %s %s panic: %v\n%s", header.GetHeaderRequestID(r), r.Method, r.URL.Path, err, stack)
+}
+
+// SetRecoverLogger 设置 DefaultConfig 中 Recover 中间件记录 panic 的方式，为 nil 时不记录
+func SetRecoverLogger(logger RecoverLogger) {
+	DefaultConfig.SetRecoverLogger(logger)
+}
+
+// Recover 恢复请求处理过程中发生的 panic，通过当前请求配置的 RecoverLogger 记录调用栈后以 mhttp.InternalServerError 响应，已注册的状态回调依旧生效
+//
+// debug 为 true 时响应体包含 panic 信息及调用栈，仅应在开发环境中使用；
+// 若响应已开始写出则不再响应，仅记录 panic；http.ErrAbortHandler 将继续向上抛出
+func Recover(debugMode bool) MiddlewareHandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := w.(mhttp.ResponseWriter); !ok {
+				w = mhttp.NewResponseWrite(w)
+			}
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				mhttp.AbortWithReason(r, fmt.Sprintf("panic: %v", err))
+
+				stack := debug.Stack()
+				if recoverLogger := ConfigOf(r).RecoverLogger(); recoverLogger != nil {
+					recoverLogger(r, err, stack)
+				}
+
+				if mhttp.ResponseStarted(w) {
+					return
+				}
+
+				if !debugMode {
+					mhttp.InternalServerError(w, r)
+					return
+				}
+
+				m := message.MessageStatusInternalServerError.Copy().Set(fmt.Sprintf("panic: %v\n\n%s", err, stack))
+				mhttp.CallRegisterFuncOrAbortError(w, r, m, http.StatusInternalServerError)
+			}()
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
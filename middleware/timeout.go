@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tangzixiang/mplus/mhttp"
+)
+
+// timeoutWrite 缓存响应的 ResponseWriter，超时后丢弃 handler 的所有写入，读写均需加锁
+type timeoutWrite struct {
+	lock sync.Mutex
+
+	header   http.Header
+	status   int
+	code     int
+	body     bytes.Buffer
+	timedOut bool
+}
+
+var _ mhttp.ResponseWriter = &timeoutWrite{}
+
+func (w *timeoutWrite) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWrite) SetStatus(status int) {
+	w.lock.Lock()
+	w.status = status
+	w.lock.Unlock()
+}
+
+func (w *timeoutWrite) Status() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.status
+}
+
+func (w *timeoutWrite) WriteHead(statusCode int) {
+	w.lock.Lock()
+	w.status = statusCode
+	w.lock.Unlock()
+	w.WriteHeader(statusCode)
+}
+
+func (w *timeoutWrite) WriteHeader(statusCode int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.timedOut || w.code != 0 {
+		return
+	}
+
+	w.code = statusCode
+}
+
+func (w *timeoutWrite) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if w.code == 0 {
+		w.code = w.status
+	}
+
+	return w.body.Write(b)
+}
+
+// Written 判断 handler 是否已开始写出响应
+func (w *timeoutWrite) Written() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.code != 0
+}
+
+// flush 将缓存的响应头、响应状态及响应体写出至 target，调用方需持有锁
+func (w *timeoutWrite) flush(target http.ResponseWriter) {
+	dst := target.Header()
+	for key, values := range w.header {
+		dst[key] = values
+	}
+
+	code := w.code
+	if code == 0 {
+		code = w.status
+	}
+
+	if rw, ok := target.(mhttp.ResponseWriter); ok {
+		rw.SetStatus(w.status)
+	}
+
+	target.WriteHeader(code)
+	_, _ = target.Write(w.body.Bytes())
+}
+
+// Timeout 请求超时中间件，为请求上下文设置超时时间，handler 未能在超时前完成时终止请求链，
+// 并根据 statusCode 通过 mhttp.GatewayTimeout 或 mhttp.ServiceUnavailable 响应，已注册的状态回调依旧生效
+//
+// statusCode 仅支持 http.StatusGatewayTimeout 及 http.StatusServiceUnavailable，为其他值时使用 http.StatusGatewayTimeout；
+// handler 在独立的 goroutine 中执行，其响应在完成后统一写出，超时后的写入将被丢弃并返回 http.ErrHandlerTimeout，
+// handler 应通过 r.Context().Done() 感知超时并尽快返回；handler 中的 panic 将在当前 goroutine 中重新抛出
+func Timeout(timeout time.Duration, statusCode int) MiddlewareHandlerFunc {
+	respond := mhttp.GatewayTimeout
+	if statusCode == http.StatusServiceUnavailable {
+		respond = mhttp.ServiceUnavailable
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			r = r.WithContext(ctx)
+			tw := &timeoutWrite{header: http.Header{}, status: http.StatusOK}
+			for key, values := range w.Header() {
+				tw.header[key] = append([]string(nil), values...)
+			}
+
+			done := make(chan struct{})
+			panicChan := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case <-done:
+				tw.lock.Lock()
+				defer tw.lock.Unlock()
+				tw.flush(w)
+			case <-ctx.Done():
+				tw.lock.Lock()
+				tw.timedOut = true
+				tw.lock.Unlock()
+
+				mhttp.AbortWithReason(r, "timeout")
+				respond(w, r)
+			}
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package mplus
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	assert "github.com/stretchr/testify/require"
 )
 
@@ -638,3 +649,1004 @@ func TestBindErrRequestValidate(t *testing.T) {
 
 	assert.Equal(t, string(body), `{"err_message":"addr not found"}`)
 }
+
+func TestETagMiddleware(t *testing.T) {
+	handler := MRote().Use(ETagMiddleware(false)).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).JSONOK(Data{"name": "tom"})
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil))
+
+	etag := w.Header().Get(HeaderEtag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, etag)
+	assert.False(t, strings.HasPrefix(etag, "W/"))
+	assert.JSONEq(t, `{"name":"tom"}`, w.Body.String())
+
+	// 弱比较命中
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderIfNoneMatch, `"other", W/`+etag)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get(HeaderEtag))
+
+	// 未命中
+	r = httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderIfNoneMatch, `"other"`)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"tom"}`, w.Body.String())
+
+	// 非 GET/HEAD 请求不处理
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://127.0.0.1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderEtag))
+}
+
+func TestETagMiddlewareWithHandlerVersion(t *testing.T) {
+	modTime := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+	hashed := false
+
+	handler := MRote().Use(ETagMiddleware(true)).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pp := PlusPlus(w, r).SetETag("v1", true).SetLastModified(modTime)
+		if pp.IsFresh() {
+			pp.NotModified()
+			return
+		}
+
+		hashed = true
+		pp.JSONOK(nil)
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `W/"v1"`, w.Header().Get(HeaderEtag))
+	assert.True(t, hashed)
+
+	// If-None-Match
+	hashed = false
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderIfNoneMatch, `"v1"`)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.False(t, hashed)
+
+	// If-Modified-Since
+	r = httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderIfModifiedSince, modTime.Add(time.Hour).Format(http.TimeFormat))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderIfModifiedSince, modTime.Add(-time.Hour).Format(http.TimeFormat))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHandleError(t *testing.T) {
+	BeforeTest(false)
+	defer AfterTest(true)
+
+	var loggedErr error
+	SetErrorLogger(func(r *http.Request, err error) { loggedErr = err })
+	defer SetErrorLogger(DefaultErrorLogger)
+
+	serve := func(err error) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		MRote().HandlerFunc(HandleError(func(w http.ResponseWriter, r *http.Request) error {
+			return err
+		})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil))
+		return w
+	}
+
+	// 无异常
+	assert.Equal(t, http.StatusOK, serve(nil).Code)
+	assert.Nil(t, loggedErr)
+
+	// 校验异常
+	var validateErr error
+	RegisterValidateErrorFunc(ErrDecode, func(w http.ResponseWriter, r *http.Request, err error) {
+		validateErr = err
+		Abort(r)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	})
+
+	w := serve(errors.Wrap(ValidateErrorWrap(errors.New("bad query"), ErrDecode), "handle order"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.True(t, validateErr.(ValidateError).IsErr(ErrDecode))
+
+	// 消息异常
+	w = serve(errors.WithMessage(NewErrCodeMessage(http.StatusConflict, 40903, "order exists"), "create order"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var callbackCode int
+	m := NewCallbackMessage(http.StatusForbidden, 40301, "forbidden", func(w http.ResponseWriter, r *http.Request, m Message, respData interface{}) {
+		callbackCode = m.ErrCode()
+		assert.Equal(t, Data{"id": 1}, respData)
+		JSON(w, r, respData, m.Status())
+	})
+
+	w = serve(m.WithData(Data{"id": 1}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 40301, callbackCode)
+
+	// 未知异常
+	unknown := errors.New("db down")
+	w = serve(unknown)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, unknown, loggedErr)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	var (
+		loggedErr   interface{}
+		loggedStack []byte
+		loggedID    string
+	)
+	app := NewApp()
+	app.SetRecoverLogger(func(r *http.Request, err interface{}, stack []byte) {
+		loggedErr, loggedStack, loggedID = err, stack, GetHeaderRequestID(r)
+	})
+	app.RegisterHttpStatusMethod(http.StatusInternalServerError, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		JSON(w, r, map[string]interface{}{"error": m.Default()}, statusCode)
+	})
+
+	type V struct{ Name string }
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_ = PlusPlus(w, r).VO().(*V)
+	}
+
+	serve := func(route *Route, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+		SetRequestHeaderRequestID(r, "req-1")
+		route.HandlerFunc(handler).ServeHTTP(w, r)
+		return w
+	}
+
+	// 通过状态回调响应
+	w := serve(MRote().WithApp(app).Use(RecoverMiddleware(false)), handler)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error":"Internal Server Error"}`, w.Body.String())
+	assert.NotNil(t, loggedErr)
+	assert.Contains(t, string(loggedStack), "TestRecoverMiddleware")
+	assert.Equal(t, "req-1", loggedID)
+
+	// debug 模式下响应体包含调用栈
+	w = serve(MRote().WithApp(NewApp()).Use(RecoverMiddleware(true)), handler)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "panic: interface conversion")
+	assert.Contains(t, w.Body.String(), "TestRecoverMiddleware")
+
+	// 响应已开始写出时不再响应
+	loggedErr = nil
+	w = serve(MRote().WithApp(app).Use(RecoverMiddleware(true)), func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Plain(http.StatusAccepted, "partial")
+		panic("late")
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+	assert.Equal(t, "late", loggedErr)
+
+	// http.ErrAbortHandler 继续向上抛出
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(MRote().Use(RecoverMiddleware(false)), func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+	})
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	route := MRote().WithApp(NewApp()).Use(CORSMiddleware(CORSConfig{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(r *http.Request, origin string) bool { return origin == "http://localhost:8080" },
+		AllowMethods:     []string{http.MethodGet, http.MethodPost},
+		ExposeHeaders:    []string{HeaderRequestID},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+
+	var called bool
+	handler := route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		PlusPlus(w, r).Plain(http.StatusOK, "ok")
+	})
+
+	serve := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		called = false
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "http://127.0.0.1/orders", nil)
+		SetRequestHeaderIf(origin != "", r, HeaderOrigin, origin)
+		SetRequestHeaders(r, headers)
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// 普通请求
+	w := serve(http.MethodGet, "https://api.example.org", nil)
+	assert.True(t, called)
+	assert.Equal(t, "https://api.example.org", w.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "true", w.Header().Get(HeaderAccessControlAllowCredentials))
+	assert.Equal(t, HeaderRequestID, w.Header().Get(HeaderAccessControlExposeHeader))
+	assert.Equal(t, []string{HeaderOrigin}, w.Header()[http.CanonicalHeaderKey(HeaderVary)])
+
+	// 自定义校验
+	w = serve(http.MethodGet, "http://localhost:8080", nil)
+	assert.Equal(t, "http://localhost:8080", w.Header().Get(HeaderAccessControlAllowOrigin))
+
+	// 不被允许的来源及无来源的请求
+	for _, origin := range []string{"https://example.org", "https://evil.com", ""} {
+		w = serve(http.MethodGet, origin, nil)
+		assert.True(t, called)
+		assert.Equal(t, "", w.Header().Get(HeaderAccessControlAllowOrigin))
+		assert.Equal(t, HeaderOrigin, w.Header().Get(HeaderVary))
+	}
+
+	// 预检请求
+	w = serve(http.MethodOptions, "https://example.com", map[string]string{
+		HeaderAccessControlRequestMethod: http.MethodPost,
+		HeaderAccessControlRequestHeader: "Content-Type, X-Token",
+	})
+	assert.False(t, called)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET, POST", w.Header().Get(HeaderAccessControlAllowMethods))
+	assert.Equal(t, "Content-Type, X-Token", w.Header().Get(HeaderAccessControlAllowHeader))
+	assert.Equal(t, "600", w.Header().Get(HeaderAccessControlMaxAge))
+	assert.Equal(t, []string{HeaderOrigin, HeaderAccessControlRequestMethod, HeaderAccessControlRequestHeader}, w.Header()[http.CanonicalHeaderKey(HeaderVary)])
+
+	w = serve(http.MethodOptions, "https://evil.com", map[string]string{HeaderAccessControlRequestMethod: http.MethodPost})
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "", w.Header().Get(HeaderAccessControlAllowOrigin))
+
+	// 允许所有来源
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/orders", nil)
+	SetRequestHeader(r, HeaderOrigin, "https://any.com")
+	MRote().Use(CORSMiddleware(CORSConfig{AllowOrigins: []string{"*"}})).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, r)
+	assert.Equal(t, "*", w.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "", w.Header().Get(HeaderVary))
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var entries []AccessLogEntry
+	sink := func(entry AccessLogEntry) { entries = append(entries, entry) }
+
+	route := MRote().WithApp(NewApp()).WithPattern("/orders/{id}").
+		Use(AccessLogMiddleware(AccessLogConfig{Sink: sink, ExcludePaths: []string{"/healthz", "/debug/*"}}))
+
+	serve := func(path string, handler http.HandlerFunc) {
+		r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1"+path, nil)
+		r.RemoteAddr = "10.0.0.1:5678"
+		SetRequestHeaderRequestID(r, "req-1")
+		SetRequestHeader(r, HeaderUserAgent, "test-agent")
+		route.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("/orders/1", func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusCreated, "created") })
+	serve("/orders/2", func(w http.ResponseWriter, r *http.Request) {
+		AbortWithReason(r, "order locked")
+		Conflict(w, r)
+	})
+	serve("/orders/3", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	serve("/orders/4", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "gone", http.StatusGone) })
+	serve("/orders/5", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+	serve("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	serve("/debug/vars", func(w http.ResponseWriter, r *http.Request) {})
+
+	assert.Len(t, entries, 5)
+	assert.Equal(t, http.MethodGet, entries[0].Method)
+	assert.Equal(t, "/orders/1", entries[0].Path)
+	assert.Equal(t, "/orders/{id}", entries[0].Route)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.Equal(t, int64(len("created")), entries[0].Bytes)
+	assert.Equal(t, "10.0.0.1", entries[0].ClientIP)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.Equal(t, "test-agent", entries[0].UserAgent)
+	assert.Equal(t, "", entries[0].AbortReason)
+
+	assert.Equal(t, http.StatusConflict, entries[1].Status)
+	assert.Equal(t, "order locked", entries[1].AbortReason)
+
+	// 直接写出的响应状态
+	assert.Equal(t, http.StatusNotFound, entries[2].Status)
+	assert.Equal(t, http.StatusGone, entries[3].Status)
+	assert.Equal(t, http.StatusOK, entries[4].Status)
+
+	// 输出格式
+	entry := AccessLogEntry{
+		Time: time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)), Method: http.MethodGet, Path: "/apache_pb.gif",
+		Proto: "HTTP/1.0", Status: http.StatusOK, Bytes: 2326, Latency: 1500 * time.Microsecond, ClientIP: "127.0.0.1",
+	}
+
+	buf := &bytes.Buffer{}
+	CommonLogSink(buf)(entry)
+	assert.Equal(t, "127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 200 2326\n", buf.String())
+
+	buf.Reset()
+	JSONLinesSink(buf)(entry)
+	var line map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, 1.5, line["latency"])
+	assert.Equal(t, float64(2326), line["bytes"])
+	assert.NotContains(t, line, "request_id")
+
+	// 采样
+	entries = nil
+	sampled := MRote().Use(AccessLogMiddleware(AccessLogConfig{Sink: sink, SampleRate: 0.5}))
+	for i := 0; i < 200; i++ {
+		sampled.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.True(t, len(entries) > 0 && len(entries) < 200)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	store := NewMemoryStore(4)
+	bucket, err := NewTokenBucket(1, 2, store)
+	assert.Nil(t, err)
+	bucket.Now = clock
+
+	app := NewApp()
+	app.RegisterHttpStatusMethod(http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		JSON(w, r, map[string]interface{}{"error": "slow down"}, statusCode)
+	})
+
+	route := MRote().WithApp(app).Use(RateLimitMiddleware(RateLimitConfig{Limiter: bucket, KeyFunc: KeyByAPIKey(HeaderAPIKey, "api_key"), Scope: "orders:"}))
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/orders?api_key="+apiKey, nil)
+		route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusOK, "ok") }).ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "1", w.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "1", w.Header().Get(HeaderRateLimitReset))
+
+	assert.Equal(t, http.StatusOK, serve("k1").Code)
+
+	w = serve("k1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.JSONEq(t, `{"error":"slow down"}`, w.Body.String())
+	assert.Equal(t, "0", w.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "1", w.Header().Get(HeaderRetryAfter))
+
+	// 不同的 key 互不影响
+	assert.Equal(t, http.StatusOK, serve("k2").Code)
+
+	// 令牌补充
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, serve("k1").Code)
+	assert.Equal(t, 2, store.Len())
+
+	// 无法获取 key 时不限流
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve("").Code)
+	}
+
+	// 滑动窗口
+	window, err := NewSlidingWindow(4, time.Minute, nil)
+	assert.Nil(t, err)
+	window.Now = clock
+	now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		result, err := window.Allow("ip")
+		assert.Nil(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3-i, result.Remaining)
+	}
+
+	result, _ := window.Allow("ip")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+
+	// 进入下一个窗口 30s 后，上一个窗口的 4 个请求按比例计为 2 个
+	now = now.Add(90 * time.Second)
+	result, _ = window.Allow("ip")
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, _ = window.Allow("ip")
+	assert.True(t, result.Allowed)
+
+	result, _ = window.Allow("ip")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	// 按客户端 IP 限流
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	byIP, _ := NewSlidingWindow(10, time.Minute, nil)
+	MRote().Use(RateLimitMiddleware(RateLimitConfig{Limiter: byIP})).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, r)
+	assert.Equal(t, "9", w.Header().Get(HeaderRateLimitRemaining))
+
+	// 状态过期时间以限流器的当前时间计算
+	_, err = bucket.Allow("expire")
+	assert.Nil(t, err)
+	state, err := store.Load("expire", now)
+	assert.Nil(t, err)
+	assert.NotNil(t, state)
+	state, _ = store.Load("expire", now.Add(2*time.Second))
+	assert.Nil(t, state)
+
+	// 无效的参数
+	_, err = (&TokenBucket{Rate: 0, Burst: 1, Store: store}).Allow("k")
+	assert.Equal(t, ErrRateLimitConfig, err)
+	_, err = (&SlidingWindow{Limit: 1, Store: store}).Allow("k")
+	assert.Equal(t, ErrRateLimitConfig, err)
+	_, err = NewTokenBucket(-1, 1, nil)
+	assert.Equal(t, ErrRateLimitConfig, errors.Cause(err))
+	_, err = NewSlidingWindow(1, 0, nil)
+	assert.Equal(t, ErrRateLimitConfig, errors.Cause(err))
+	assert.Panics(t, func() { RateLimitMiddleware(RateLimitConfig{}) })
+
+	// 外部存储以序列化的状态及 CompareAndSwap 更新，并发更新冲突时重试
+	external := &conflictStore{memory: NewMemoryStore(1), conflicts: 1}
+	bucket = &TokenBucket{Rate: 0.001, Burst: 10, Store: external}
+
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				result, err := bucket.Allow("k")
+				if err == ErrRateLimitConflict {
+					continue
+				}
+				assert.Nil(t, err)
+
+				lock.Lock()
+				if result.Allowed {
+					allowed++
+				}
+				lock.Unlock()
+				return
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, allowed)
+
+	state, _ = external.Load("k", time.Now())
+	var bucketState TokenBucketState
+	assert.Nil(t, json.Unmarshal(state, &bucketState))
+	assert.True(t, bucketState.Tokens < 1)
+}
+
+func TestRateLimitMiddleware_Concurrent(t *testing.T) {
+	const burst = 5
+
+	bucket, err := NewTokenBucket(0.001, burst, nil)
+	assert.Nil(t, err)
+
+	route := MRote().WithApp(NewApp()).Use(RateLimitMiddleware(RateLimitConfig{Limiter: bucket, KeyFunc: KeyByHeader(HeaderAPIKey)}))
+	handler := route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusOK, "ok") })
+
+	var (
+		wg    sync.WaitGroup
+		lock  sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(HeaderAPIKey, "k")
+			handler.ServeHTTP(w, r)
+
+			lock.Lock()
+			codes[w.Code]++
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[int]int{http.StatusOK: burst, http.StatusTooManyRequests: 100 - burst}, codes)
+
+	// 外部存储始终冲突或不可用时拒绝请求
+	serve := func(store RateLimitStore) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(HeaderAPIKey, "k")
+		MRote().WithApp(NewApp()).Use(RateLimitMiddleware(RateLimitConfig{Limiter: &TokenBucket{Rate: 1, Burst: 1, Store: store}, KeyFunc: KeyByHeader(HeaderAPIKey)})).
+			HandlerFunc(func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).Plain(http.StatusOK, "ok") }).ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(&conflictStore{memory: NewMemoryStore(1), conflicts: 1 << 10})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(HeaderRetryAfter))
+
+	w = serve(failingStore{})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get(HeaderRetryAfter))
+}
+
+// failingStore 模拟不可用的外部存储
+type failingStore struct{}
+
+func (failingStore) Load(key string, now time.Time) ([]byte, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func (failingStore) CompareAndSwap(key string, old, state []byte, now time.Time, ttl time.Duration) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+// conflictStore 模拟仅支持 Load 及 CompareAndSwap 的外部存储，前 conflicts 次 CompareAndSwap 前由其他实例写入状态
+type conflictStore struct {
+	memory *MemoryStore
+
+	lock      sync.Mutex
+	conflicts int
+}
+
+func (s *conflictStore) Load(key string, now time.Time) ([]byte, error) {
+	return s.memory.Load(key, now)
+}
+
+func (s *conflictStore) CompareAndSwap(key string, old, state []byte, now time.Time, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	conflicts := s.conflicts
+	s.conflicts--
+	s.lock.Unlock()
+
+	if conflicts > 0 {
+		// 每次写入不同的状态，避免与重试时计算的状态相同
+		last := now.Add(-time.Duration(conflicts)).Format(time.RFC3339Nano)
+		if _, err := s.memory.CompareAndSwap(key, old, []byte(`{"tokens":10,"last":"`+last+`"}`), now, ttl); err != nil {
+			return false, err
+		}
+	}
+
+	return s.memory.CompareAndSwap(key, old, state, now, ttl)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	app := NewApp()
+	app.RegisterHttpStatusMethod(http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		JSON(w, r, map[string]interface{}{"error": "busy"}, statusCode)
+	})
+
+	// 未超时
+	w := httptest.NewRecorder()
+	MRote().WithApp(app).Timeout(time.Second, http.StatusServiceUnavailable).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetResponseHeader(w, HeaderRequestID, "req-1")
+		PlusPlus(w, r).Plain(http.StatusCreated, "created")
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "created", w.Body.String())
+	assert.Equal(t, "req-1", w.Header().Get(HeaderRequestID))
+
+	// 超时后通过状态回调响应，handler 的写入被丢弃
+	lateWrite := make(chan error, 1)
+	var reason string
+
+	w = httptest.NewRecorder()
+	MRote().WithApp(app).Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			reason = AbortReason(r)
+		}
+	}).Timeout(20*time.Millisecond, http.StatusServiceUnavailable).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		for i := 0; i < 100; i++ {
+			SetContextValue(r.Context(), "late", i)
+			_ = IsAbort(r)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("late"))
+		lateWrite <- err
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"busy"}`, w.Body.String())
+	assert.Equal(t, "timeout", reason)
+	assert.Equal(t, http.ErrHandlerTimeout, <-lateWrite)
+
+	// 默认以 504 响应
+	w = httptest.NewRecorder()
+	MRote().WithApp(NewApp()).Timeout(10*time.Millisecond, 0).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	// panic 在当前 goroutine 中重新抛出
+	w = httptest.NewRecorder()
+	MRote().WithApp(NewApp()).Use(RecoverMiddleware(false)).Timeout(time.Second, 0).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestIPFilterMiddleware(t *testing.T) {
+	set, err := NewIPSet("192.168.1.0/24", "192.168.0.0/16", "10.1.2.3", "2001:db8::/32")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, set.Len())
+
+	for ip, want := range map[string]bool{
+		"192.168.200.1": true, "10.1.2.3": true, "10.1.2.4": false, "2001:db8::1": true, "2001:db9::1": false, "::ffff:10.1.2.3": true,
+	} {
+		assert.Equal(t, want, set.Contains(net.ParseIP(ip)), ip)
+	}
+
+	_, err = NewIPSet("10.0.0.0/33")
+	assert.NotNil(t, err)
+
+	allow, _ := NewIPList("10.0.0.0/8", "fd00::/8")
+	deny, _ := NewIPList("10.0.0.13")
+
+	app := NewApp()
+	route := MRote().WithApp(app).IPFilter(allow, deny)
+	serve := func(route *Route, remoteAddr string, forwardedFor ...string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		r.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			r.Header.Add(HeaderForwardedFor, value)
+		}
+		route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(route, "10.0.0.1:1234"))
+	assert.Equal(t, http.StatusOK, serve(route, "[fd00::1]:1234"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "10.0.0.13:1234"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "8.8.8.8:1234"))
+	assert.Equal(t, http.StatusOK, serve(MRote().WithApp(NewApp()).IPFilter(nil, deny), "8.8.8.8:1234"))
+
+	// 伪造的 X-Forwarded-For 不被采用
+	assert.Equal(t, http.StatusForbidden, serve(route, "8.8.8.8:1234", "10.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "10.0.0.13:1234", "10.0.0.1"))
+
+	// 直连地址为受信任的代理时使用解析出的客户端 IP
+	assert.Nil(t, app.SetTrustedProxies("172.16.0.1"))
+	assert.Equal(t, http.StatusOK, serve(route, "172.16.0.1:1234", "10.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "172.16.0.1:1234", "10.0.0.13"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "8.8.8.8:1234", "10.0.0.1"))
+	assert.Nil(t, SetTrustedProxies("172.16.0.1"))
+	assert.Equal(t, http.StatusForbidden, serve(MRote().WithApp(NewApp()).IPFilter(allow, deny), "172.16.0.1:1234", "10.0.0.1"))
+	assert.Nil(t, SetTrustedProxies())
+	assert.Nil(t, app.SetTrustedProxies())
+
+	// 热替换
+	set, _ = ReadIPSet(strings.NewReader("# office\n8.8.8.0/24 # dns\n\n"))
+	allow.Store(set)
+	assert.Equal(t, http.StatusOK, serve(route, "8.8.8.8:1234"))
+	assert.Equal(t, http.StatusForbidden, serve(route, "10.0.0.1:1234"))
+
+	// 从文件重新加载
+	dir, err := ioutil.TempDir("", "ipfilter")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "allow.txt")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("10.0.0.0/8\n"), 0644))
+
+	_, err = allow.WatchFile(file, 0, nil)
+	assert.Equal(t, ErrWatchInterval, errors.Cause(err))
+
+	var (
+		reloadLock sync.Mutex
+		reloadErr  error
+	)
+	stop, err := allow.WatchFile(file, 5*time.Millisecond, func(err error) {
+		reloadLock.Lock()
+		reloadErr = err
+		reloadLock.Unlock()
+	})
+	assert.Nil(t, err)
+	defer stop()
+	assert.Equal(t, http.StatusOK, serve(route, "10.0.0.1:1234"))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte("10.0.0.0/8\n172.16.0.0/12\n"), 0644))
+	for i := 0; i < 200 && allow.Len() != 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, 2, allow.Len())
+	reloadLock.Lock()
+	assert.Nil(t, reloadErr)
+	reloadLock.Unlock()
+	assert.Equal(t, http.StatusOK, serve(route, "172.16.5.5:1234"))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tokens := map[string]*Principal{"t-1": {ID: "alice", Scopes: []string{"orders:read"}}}
+	route := MRote().WithApp(NewApp()).Use(AuthMiddleware(
+		BasicAuth("api", BasicUsers(map[string]string{"bob": "secret"})),
+		BearerAuth("api", func(r *http.Request, token string) (*Principal, error) {
+			return tokens[token], nil
+		}),
+		APIKeyAuth(HeaderAPIKey, "api_key", func(r *http.Request, key string) (*Principal, error) {
+			if key != "k-1" {
+				return nil, ErrInvalidCredentials
+			}
+			return &Principal{ID: "service"}, nil
+		}),
+	))
+
+	var principal *Principal
+	serve := func(setup func(r *http.Request)) *httptest.ResponseRecorder {
+		principal = nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		setup(r)
+		route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PlusPlus(w, r).Principal()
+		}).ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(func(r *http.Request) { r.SetBasicAuth("bob", "secret") })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", principal.ID)
+	assert.Equal(t, "Basic", principal.Scheme)
+
+	w = serve(func(r *http.Request) { r.Header.Set(HeaderAuthorization, "Bearer t-1") })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", principal.ID)
+	assert.Equal(t, "Bearer", principal.Scheme)
+	assert.True(t, principal.HasScope("orders:read"))
+	// 校验函数返回的共享主体不会被修改
+	assert.Equal(t, "", tokens["t-1"].Scheme)
+
+	w = serve(func(r *http.Request) { r.URL.RawQuery = "api_key=k-1" })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "service", principal.ID)
+	assert.Equal(t, "APIKey", principal.Scheme)
+
+	// 未携带凭证时返回所有认证方式的质询
+	w = serve(func(r *http.Request) {})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, principal)
+	assert.Equal(t, []string{`Basic realm="api", charset="UTF-8"`, `Bearer realm="api"`, `APIKey header="X-Api-Key", query="api_key"`},
+		w.Header()[http.CanonicalHeaderKey(HeaderWWWAuthenticate)])
+
+	// Basic 认证失败时 Bearer 的质询不携带错误信息
+	w = serve(func(r *http.Request) { r.SetBasicAuth("bob", "wrong") })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, principal)
+	assert.Equal(t, `Bearer realm="api"`, w.Header()[http.CanonicalHeaderKey(HeaderWWWAuthenticate)][1])
+
+	// 质询只包含固定的错误描述，详细原因仅记录为中断原因
+	var reason string
+	detailed := MRote().WithApp(NewApp()).Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, r)
+			reason = AbortReason(r)
+		}
+	}).Use(AuthMiddleware(BearerAuth("api", func(r *http.Request, token string) (*Principal, error) {
+		return nil, errors.New("token revoked by admin@example.com")
+	})))
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set(HeaderAuthorization, "Bearer t-2")
+	detailed.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="api", error="invalid_token", error_description="The access token is invalid"`, w.Header().Get(HeaderWWWAuthenticate))
+	assert.Equal(t, "token revoked by admin@example.com", reason)
+
+	// 请求上下文中不存在 mplus 上下文时，主体记录至返回的请求
+	r = httptest.NewRequest(http.MethodGet, "/orders", nil)
+	assert.Equal(t, "alice", GetPrincipal(SetPrincipal(r, &Principal{ID: "alice"})).ID)
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	now := time.Unix(1700000000, 0)
+	config := JWTConfig{
+		Keys:      JWTKeys{{ID: "hs", Key: []byte("secret")}, {ID: "rs", Key: &rsaKey.PublicKey}, {ID: "es", Key: &ecKey.PublicKey}},
+		Issuer:    "mplus",
+		Audience:  []string{"orders"},
+		ClockSkew: 30 * time.Second,
+		Now:       func() time.Time { return now },
+	}
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "alice", "iss": "mplus", "aud": []string{"orders", "billing"}, "exp": now.Unix() + 60, "scope": "orders:read orders:write", "tenant": 42}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	sign := func(c map[string]interface{}, alg string, key JWTKey) string {
+		token, err := SignJWT(c, alg, key)
+		assert.Nil(t, err)
+		return token
+	}
+
+	for alg, key := range map[string]JWTKey{
+		JWTAlgHS256: {ID: "hs", Key: []byte("secret")},
+		JWTAlgHS384: {ID: "hs", Key: []byte("secret")},
+		JWTAlgHS512: {ID: "hs", Key: []byte("secret")},
+		JWTAlgRS256: {ID: "rs", Key: rsaKey},
+		JWTAlgES256: {ID: "es", Key: ecKey},
+	} {
+		parsed, err := ParseJWT(sign(claims(nil), alg, key), config)
+		assert.Nil(t, err, alg)
+		assert.Equal(t, "alice", parsed.Subject(), alg)
+	}
+
+	hs := JWTKey{ID: "hs", Key: []byte("secret")}
+	for err, c := range map[error]map[string]interface{}{
+		ErrTokenExpired:     claims(map[string]interface{}{"exp": now.Unix() - 31}),
+		ErrTokenNotYetValid: claims(map[string]interface{}{"nbf": now.Unix() + 31}),
+		ErrTokenIssuer:      claims(map[string]interface{}{"iss": "other"}),
+		ErrTokenAudience:    claims(map[string]interface{}{"aud": "billing"}),
+	} {
+		_, parseErr := ParseJWT(sign(c, JWTAlgHS256, hs), config)
+		assert.Equal(t, err, errors.Cause(parseErr))
+	}
+
+	// 允许的时钟偏差内
+	_, err = ParseJWT(sign(claims(map[string]interface{}{"exp": now.Unix() - 29, "nbf": now.Unix() + 29}), JWTAlgHS256, hs), config)
+	assert.Nil(t, err)
+
+	// 篡改签名、错误的密钥及算法
+	token := sign(claims(nil), JWTAlgHS256, hs)
+	_, err = ParseJWT(token[:len(token)-2]+"AA", config)
+	assert.Equal(t, ErrTokenSignature, errors.Cause(err))
+	_, err = ParseJWT(sign(claims(nil), JWTAlgHS256, JWTKey{ID: "hs", Key: []byte("other")}), config)
+	assert.Equal(t, ErrTokenSignature, errors.Cause(err))
+	_, err = ParseJWT(sign(claims(nil), JWTAlgHS256, JWTKey{ID: "rs", Key: []byte("secret")}), config)
+	assert.Equal(t, ErrTokenKeyNotFound, errors.Cause(err))
+	_, err = ParseJWT(strings.Replace(token, token[:strings.Index(token, ".")], "eyJhbGciOiJub25lIn0", 1), config)
+	assert.Equal(t, ErrTokenAlgorithm, errors.Cause(err))
+	_, err = ParseJWT("not.a-token", config)
+	assert.Equal(t, ErrTokenMalformed, errors.Cause(err))
+
+	// 声明映射至结构体
+	var principal *Principal
+	route := MRote().WithApp(NewApp()).Use(JWTMiddleware(config))
+	serve := func(token string) *httptest.ResponseRecorder {
+		principal = nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		r.Header.Set(HeaderAuthorization, "Bearer "+token)
+		route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PlusPlus(w, r).Principal()
+			assert.Equal(t, "alice", JWTClaimsOf(r).Subject())
+		}).ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(sign(claims(nil), JWTAlgES256, JWTKey{ID: "es", Key: ecKey}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", principal.ID)
+	assert.True(t, principal.HasScope("orders:write"))
+
+	var user struct {
+		Subject  string   `json:"sub"`
+		Audience []string `json:"aud"`
+		Tenant   int      `json:"tenant"`
+	}
+	assert.Nil(t, principal.DecodeClaims(&user))
+	assert.Equal(t, "alice", user.Subject)
+	assert.Equal(t, []string{"orders", "billing"}, user.Audience)
+	assert.Equal(t, 42, user.Tenant)
+
+	w = serve(sign(claims(map[string]interface{}{"exp": now.Unix() - 60}), JWTAlgHS256, hs))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, principal)
+	assert.Contains(t, w.Header().Get(HeaderWWWAuthenticate), `error="invalid_token"`)
+
+	// 从 JWKS 文件及 handler 加载密钥
+	jwks, err := MarshalJWKS(JWTKey{ID: "rs", Key: rsaKey}, JWTKey{ID: "es", Algorithm: JWTAlgES256, Key: ecKey})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(jwks), `"d"`)
+
+	dir, err := ioutil.TempDir("", "jwks")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "jwks.json")
+	assert.Nil(t, ioutil.WriteFile(file, jwks, 0644))
+
+	fileConfig := config
+	fileConfig.Keys = NewJWKSFile(file, time.Minute)
+	_, err = ParseJWT(sign(claims(nil), JWTAlgRS256, JWTKey{ID: "rs", Key: rsaKey}), fileConfig)
+	assert.Nil(t, err)
+	_, err = ParseJWT(sign(claims(nil), JWTAlgHS256, hs), fileConfig)
+	assert.Equal(t, ErrTokenKeyNotFound, errors.Cause(err))
+
+	var loads int
+	handlerConfig := config
+	handlerConfig.Keys = NewJWKSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loads++
+		_, _ = w.Write(jwks)
+	}), time.Minute)
+	for i := 0; i < 3; i++ {
+		_, err = ParseJWT(sign(claims(nil), JWTAlgES256, JWTKey{ID: "es", Key: ecKey}), handlerConfig)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, loads)
+
+	// 并发获取密钥时只加载一次
+	var (
+		loadLock sync.Mutex
+		started  = make(chan struct{})
+		release  = make(chan struct{})
+	)
+	loads = 0
+	concurrent := NewJWKSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loadLock.Lock()
+		if loads++; loads == 1 {
+			close(started)
+		}
+		loadLock.Unlock()
+
+		<-release
+		_, _ = w.Write(jwks)
+	}), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := concurrent.Key("es", JWTAlgES256)
+			assert.Nil(t, err)
+			assert.NotNil(t, key)
+		}()
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 1, loads)
+
+	// 加载过程中再次获取密钥不会死锁，如 JWKS 接口自身需要 JWT 认证
+	var nested *JWKSCache
+	nested = NewJWKSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := nested.Key("es", JWTAlgES256)
+		assert.NotNil(t, err)
+		_, _ = w.Write(jwks)
+	}), time.Minute)
+	nested.LoadTimeout = 10 * time.Millisecond
+
+	_, err = nested.Key("es", JWTAlgES256)
+	assert.Nil(t, err)
+
+	// handler 响应失败
+	failed := NewJWKSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}), time.Minute)
+	_, err = failed.Key("es", JWTAlgES256)
+	assert.NotNil(t, err)
+}
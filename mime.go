@@ -17,6 +17,7 @@ const (
 	MIMEMSGPACK           = mime.MIMEMSGPACK
 	MIMEMSGPACK2          = mime.MIMEMSGPACK2
 	MIMEStream            = mime.MIMEStream
+	MIMEProblemJSON       = mime.MIMEProblemJSON
 )
 
 var ParseMediaType = mime.ParseMediaType
@@ -20,6 +20,7 @@ const (
 	MIMEMSGPACK           = header.ContentTypeMSGPACK
 	MIMEMSGPACK2          = header.ContentTypeMSGPACK2
 	MIMEStream            = header.ContentTypeStream
+	MIMEProblemJSON       = header.ContentTypeProblemJSON
 )
 
 // ParseMediaType 解析 Header 中的 Content-Type
@@ -2,9 +2,14 @@ package plus
 
 import (
 	"encoding/json"
+	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/tangzixiang/mplus/context"
@@ -43,6 +48,16 @@ func (p *PP) RequestID() string {
 	return header.GetHeaderRequestID(p.r)
 }
 
+// Lang 获取当前请求的语言，见 mhttp.RequestLang
+func (p *PP) Lang() message.MSGType {
+	return mhttp.RequestLang(p.r)
+}
+
+// Text 获取消息在当前请求语言下的内容
+func (p *PP) Text(m message.Message) string {
+	return mhttp.LocalizeMessage(p.r, m).Default()
+}
+
 // VO 获取请求对象，需要同时使用 mplus 的 bind 机制
 func (p *PP) VO() interface{} {
 	return context.GetContextValue(p.r.Context(), context.ReqData)
@@ -65,15 +80,15 @@ func (p *PP) IsAbort() bool {
 	return mhttp.IsAbort(p.r)
 }
 
-// Error 响应异常信息
+// Error 响应异常信息，已通过 mplus.RegisterHttpStatusMethod 等方式注册对应状态码的状态回调时调用该回调
 func (p *PP) Error(statusCode int, msg string) *PP {
-	mhttp.Error(p.w, message.NewMessage(statusCode, msg))
+	mhttp.CallRegisterFuncOrError(p.w, p.r, message.NewMessage(statusCode, msg), statusCode)
 	return p
 }
 
-// EmptyError 响应空的异常信息
+// EmptyError 响应空的异常信息，已通过 mplus.RegisterHttpStatusMethod 等方式注册对应状态码的状态回调时调用该回调
 func (p *PP) EmptyError(statusCode int) *PP {
-	mhttp.ErrorEmpty(p.w, message.NewMessage(statusCode, ""))
+	mhttp.CallRegisterFuncOrEmptyError(p.w, p.r, message.NewMessage(statusCode, ""), statusCode)
 	return p
 }
 
@@ -83,21 +98,26 @@ func (p *PP) AbortEmptyError(statusCode int) *PP {
 	return p
 }
 
-// ErrorMsg 响应异常信息
+// ErrorMsg 响应异常信息，开启 problem+json 模式后状态码为 4xx/5xx 时以 application/problem+json 格式响应
 func (p *PP) ErrorMsg(message message.Message) *PP {
-	mhttp.Error(p.w, message)
+	if mhttp.IsProblem(p.r, message) {
+		mhttp.ProblemJSON(p.w, p.r, message)
+		return p
+	}
+
+	mhttp.Error(p.w, mhttp.LocalizeMessage(p.r, message))
 	return p
 }
 
-// AbortErrorMsg 将当前请求标识为中断并响应异常信息
+// AbortErrorMsg 将当前请求标识为中断并响应异常信息，开启 problem+json 模式后状态码为 4xx/5xx 时以 application/problem+json 格式响应
 func (p *PP) AbortErrorMsg(message message.Message) *PP {
 	mhttp.AbortError(p.w, p.r, message)
 	return p
 }
 
-// Plain 返回一个 text/plain 格式的响应
+// Plain 返回一个 text/plain 格式的响应，已通过 mplus.RegisterHttpStatusMethod 等方式注册对应状态码的状态回调时调用该回调
 func (p *PP) Plain(statusCode int, msg string) *PP {
-	mhttp.Plain(p.w, message.NewMessage(statusCode, msg))
+	mhttp.CallRegisterFuncOrPlain(p.w, p.r, message.NewMessage(statusCode, msg), statusCode)
 	return p
 }
 
@@ -113,15 +133,21 @@ func (p *PP) Redirect(url string, statusCode int) *PP {
 	return p
 }
 
-// JSON 响应指定状态码的 JSON 数据
+// JSON 响应指定状态码的 JSON 数据，若当前请求配置已设置响应信封（见 mplus.SetEnvelope）则以信封格式响应
 func (p *PP) JSON(data interface{}, status int) *PP {
-	mhttp.JSON(p.w, p.r, data, status)
+	mhttp.JSONEnvelope(p.w, p.r, message.StatusMessage(status), data)
 	return p
 }
 
-// JSONOK 响应指定状态码为 200 的 JSON 数据
+// JSONOK 响应指定状态码为 200 的 JSON 数据，若当前请求配置已设置响应信封（见 mplus.SetEnvelope）则以信封格式响应
 func (p *PP) JSONOK(data interface{}) *PP {
-	mhttp.JSONOK(p.w, p.r, data)
+	mhttp.JSONEnvelope(p.w, p.r, message.MessageStatusOK, data)
+	return p
+}
+
+// JSONMsg 以 m 的状态码响应 JSON 数据，若当前请求配置已设置响应信封（见 mplus.SetEnvelope）则以信封格式响应，信封的 code 及 message 取自 m
+func (p *PP) JSONMsg(m message.Message, data interface{}) *PP {
+	mhttp.JSONEnvelope(p.w, p.r, m, data)
 	return p
 }
 
@@ -772,16 +798,23 @@ func (p *PP) GetFloat64Df(key string, defaultValue float64) float64 {
 }
 
 // DoCallback 查询指定错误嘛注册的回调并执行
+//
+// 回调接收的消息为当前请求语言的拷贝，若消息未注册回调且当前请求配置已设置响应信封（见 mplus.SetEnvelope），则以信封格式响应 respData
 func (p *PP) CallbackByCode(errorCode int, respData interface{}) *PP {
 	mByCode := message.Messages.Get(errorCode)
 
-	if mByCode != nil {
-		if m, ok := mByCode.(message.Callback); ok {
-			m.Do(p.w, p.r, mByCode, respData)
-			return p
-		}
+	if mByCode == nil {
+		return p
 	}
 
+	mByCode = mhttp.LocalizeMessage(p.r, mByCode)
+
+	if !mByCode.HasCallback() && mhttp.ConfigOf(p.r).Envelope() != nil {
+		mhttp.JSONEnvelope(p.w, p.r, mByCode, respData)
+		return p
+	}
+
+	mByCode.Do(p.w, p.r, mByCode, respData)
 	return p
 }
 
@@ -973,6 +1006,104 @@ func (p *PP) GetStatus() int {
 	return mhttp.GetHTTPRespStatus(p.w)
 }
 
+// SetETag 设置响应头 ETag，tag 未携带双引号时自动补全，weak 为 true 时设置弱校验 ETag
+//
+// 配合 mplus.ETagMiddleware 使用时，已设置 ETag 的响应不再计算响应体摘要
+func (p *PP) SetETag(tag string, weak bool) *PP {
+	header.SetResponseHeader(p.w, header.Etag, mhttp.FormatETag(tag, weak))
+	return p
+}
+
+// SetLastModified 设置响应头 Last-Modified
+func (p *PP) SetLastModified(modTime time.Time) *PP {
+	header.SetResponseHeader(p.w, header.LastModified, modTime.UTC().Format(http.TimeFormat))
+	return p
+}
+
+// IsFresh 根据已设置的响应头 ETag 及 Last-Modified 判断请求携带的 If-None-Match 或 If-Modified-Since 是否命中，
+// 命中时客户端缓存仍然有效，可直接响应 p.NotModified() 以避免生成响应内容
+func (p *PP) IsFresh() bool {
+	return mhttp.CheckNotModified(p.r, header.GetResponseHeader(p.w, header.Etag), mhttp.ResponseLastModified(p.w))
+}
+
+// Precondition 校验写请求携带的 If-Match 或 If-Unmodified-Since 是否与当前资源版本一致，etag 及 modTime 为当前资源的版本信息
+//
+// 条件不满足时响应 412 Precondition Failed，当前路由通过 mplus.Route.RequirePrecondition 要求必须携带条件而请求未携带时响应 428 Precondition Required，
+// 以上两种情况均返回 false，handler 应直接返回
+func (p *PP) Precondition(etag string, modTime time.Time) bool {
+	if etag != "" {
+		etag = mhttp.FormatETag(etag, strings.HasPrefix(etag, "W/"))
+	}
+
+	switch mhttp.CheckPrecondition(p.r, etag, modTime, mhttp.IsPreconditionRequired(p.r)) {
+	case http.StatusPreconditionFailed:
+		p.PreconditionFailed()
+		return false
+	case http.StatusPreconditionRequired:
+		p.PreconditionRequired()
+		return false
+	}
+
+	return true
+}
+
+// File 以流的方式响应指定路径的文件，支持 Range 及 If-Range 断点续传，文件不存在时响应 404
+func (p *PP) File(path string) *PP {
+	f, err := os.Open(path)
+	if err != nil {
+		return p.fileErr(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return p.fileErr(err)
+	}
+
+	if info.IsDir() {
+		return p.NotFound()
+	}
+
+	mhttp.ServeContent(p.w, p.r, info.Name(), info.ModTime(), f)
+	return p
+}
+
+func (p *PP) fileErr(err error) *PP {
+	switch {
+	case os.IsNotExist(err):
+		return p.NotFound()
+	case os.IsPermission(err):
+		return p.Forbidden()
+	default:
+		return p.InternalServerError()
+	}
+}
+
+// Attachment 以附件的方式响应 reader 的内容，filename 会按 RFC 5987 编码写入 Content-Disposition，modTime 非零值时设置 Last-Modified，
+// reader 实现了 io.ReadSeeker 时支持 Range 及 If-Range 断点续传，否则以流的方式响应完整内容，内容类型根据 filename 的扩展名推断
+func (p *PP) Attachment(reader io.Reader, filename string, modTime time.Time) *PP {
+	header.SetResponseHeader(p.w, header.ContentDisposition, header.FormatContentDisposition(header.DispositionAttachment, filename))
+
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		mhttp.ServeContent(p.w, p.r, filename, modTime, seeker)
+		return p
+	}
+
+	if !modTime.IsZero() {
+		header.SetResponseHeader(p.w, header.LastModified, modTime.UTC().Format(http.TimeFormat))
+	}
+
+	mhttp.Stream(p.w, p.r, reader, mime.TypeByExtension(filepath.Ext(filename)))
+	return p
+}
+
+// Stream 以流的方式响应 reader 的内容，contentType 为空时默认为 application/octet-stream，
+// reader 实现了 io.ReadSeeker 时支持 Range 及 If-Range 断点续传
+func (p *PP) Stream(reader io.Reader, contentType string) *PP {
+	mhttp.Stream(p.w, p.r, reader, contentType)
+	return p
+}
+
 // CopyReq 拷贝一个请求
 func (p *PP) CopyReq() *http.Request {
 	return mhttp.CopyRequest(p.r)
@@ -983,24 +1114,55 @@ func (p *PP) Method() string {
 	return p.r.Method
 }
 
-// GetClientIP 获取客户端 IP 地址
+// GetClientIP 获取客户端 IP 地址，默认使用直连地址，通过 mplus.SetTrustedProxies 设置受信任的代理后仅信任来自代理的转发请求头
 func (p *PP) GetClientIP() string {
 	return header.GetClientIP(p.r)
 }
 
-// ReqBody 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用
+// GetScheme 获取客户端请求使用的协议，http 或 https
+func (p *PP) GetScheme() string {
+	return header.GetScheme(p.r)
+}
+
+// GetHost 获取客户端请求的主机名
+func (p *PP) GetHost() string {
+	return header.GetHost(p.r)
+}
+
+// Principal 获取当前请求认证通过的主体，未认证时返回 nil
+func (p *PP) Principal() *mhttp.Principal {
+	return mhttp.GetPrincipal(p.r)
+}
+
+// ReqBody 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用，携带 Content-Encoding 的 body 会先被解压，
+// 解压失败（如不支持的编码、超出 MaxDecompressSize）或读取失败时忽略错误并返回原始内容，需要错误时使用 ReqBodyE
 func (p *PP) ReqBody() string {
-	return mhttp.DumpRequest(p.r)
+	return string(p.ReqBodyPure())
 }
 
-// ReqBody 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用
+// ReqBodyPure 与 ReqBody 相同，返回 []byte，需要错误时使用 ReqBodyE
 func (p *PP) ReqBodyPure() []byte {
+	_ = mhttp.DecodeRequestBody(p.r)
 	return mhttp.DumpRequestPure(p.r)
 }
 
+// ReqBodyE 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用，携带 Content-Encoding 的 body 会先被解压，
+// 解压失败时返回 mhttp.ErrContentEncoding、mhttp.ErrBodyTooLarge 等错误，读取失败时返回对应的错误
+func (p *PP) ReqBodyE() ([]byte, error) {
+	if err := mhttp.DecodeRequestBody(p.r); err != nil {
+		return nil, err
+	}
+
+	return mhttp.ReadRequestBody(p.r)
+}
+
 // ReqBodyMap 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用,body 内容会被序列化成 map[string] interface{}
 func (p *PP) ReqBodyMap() (map[string]interface{}, error) {
-	body := mhttp.DumpRequestPure(p.r)
+	body, err := p.ReqBodyE()
+	if err != nil {
+		return nil, err
+	}
+
 	m := map[string]interface{}{}
 
 	return m, json.Unmarshal(body, &m)
@@ -1008,7 +1170,12 @@ func (p *PP) ReqBodyMap() (map[string]interface{}, error) {
 
 // ReqBodyMap 读取 p.r 的 body 内容并保持 p.r.Body 可持续使用,body 内容会被序列化至 unmarshaler
 func (p *PP) ReqBodyToUnmarshaler(unmarshaler json.Unmarshaler) error {
-	return unmarshaler.UnmarshalJSON(mhttp.DumpRequestPure(p.r))
+	body, err := p.ReqBodyE()
+	if err != nil {
+		return err
+	}
+
+	return unmarshaler.UnmarshalJSON(body)
 }
 
 // SetCookie 添加 cookie 信息
@@ -2,12 +2,19 @@ package mplus
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	assert "github.com/stretchr/testify/require"
 )
@@ -82,3 +89,359 @@ func TestPP_ReqBodyToUnmarshaler(t *testing.T) {
 
 	assert.Equal(t, contentBytes, bodyBytes)
 }
+
+func TestPP_ReqBodyGzip(t *testing.T) {
+	contentBytes := []byte(`{"name":"tom","age":18}`)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write(contentBytes)
+	assert.Nil(t, err)
+	assert.Nil(t, gw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080", &buf)
+	SetRequestHeader(req, HeaderContentEncoding, "gzip")
+
+	pp := PlusPlus(httptest.NewRecorder(), req)
+
+	bodyM, err := pp.ReqBodyMap()
+	assert.Nil(t, err)
+	assert.Equal(t, "tom", bodyM["name"])
+
+	// 	read again
+	assert.Equal(t, string(contentBytes), pp.ReqBody())
+
+	// 不支持的编码：ReqBody 返回原始内容，ReqBodyE 返回错误
+	req = httptest.NewRequest(http.MethodPost, "http://localhost:8080", strings.NewReader("compressed"))
+	SetRequestHeader(req, HeaderContentEncoding, "br")
+	pp = PlusPlus(httptest.NewRecorder(), req)
+
+	_, err = pp.ReqBodyE()
+	assert.Equal(t, ErrContentEncoding, err)
+	assert.Equal(t, "compressed", pp.ReqBody())
+
+	// 压缩的内容超出限制时不会完整读入内存，r.Body 恢复为原始内容
+	raw := bytes.Repeat([]byte("x"), 64)
+	app := NewApp()
+	app.SetMaxDecompressSize(16)
+	req = httptest.NewRequest(http.MethodPost, "http://localhost:8080", bytes.NewReader(raw))
+	SetRequestHeader(req, HeaderContentEncoding, "gzip")
+	pp = PlusPlus(httptest.NewRecorder(), req.WithContext(app.WithContext(req.Context())))
+
+	_, err = pp.ReqBodyE()
+	assert.EqualError(t, err, "decompressed body too large")
+	assert.Equal(t, raw, DumpRequestPure(pp.Req()))
+
+	// 读取失败
+	req = httptest.NewRequest(http.MethodPost, "http://localhost:8080", ioutil.NopCloser(io.MultiReader(strings.NewReader("{"), errorReader{})))
+	SetRequestHeader(req, HeaderContentEncoding, "gzip")
+	_, err = PlusPlus(httptest.NewRecorder(), req).ReqBodyE()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+// errorReader 读取时总是返回 io.ErrUnexpectedEOF
+type errorReader struct{}
+
+func (errorReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestPP_Precondition(t *testing.T) {
+	modTime := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	newPP := func(mr *Route, headers map[string]string) (*httptest.ResponseRecorder, bool) {
+		var passed bool
+		r := httptest.NewRequest(http.MethodPut, "http://127.0.0.1", nil)
+		SetRequestHeaders(r, headers)
+
+		w := httptest.NewRecorder()
+		mr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			passed = PlusPlus(w, r).Precondition("v2", modTime)
+		}).ServeHTTP(w, r)
+
+		return w, passed
+	}
+
+	mr := MRote()
+
+	// 未要求必须携带条件
+	w, passed := newPP(mr, nil)
+	assert.True(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, passed = newPP(mr, map[string]string{HeaderIfMatch: `"v1", "v2"`})
+	assert.True(t, passed)
+
+	w, passed = newPP(mr, map[string]string{HeaderIfMatch: `"v1"`})
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// If-Match 使用强比较
+	w, passed = newPP(mr, map[string]string{HeaderIfMatch: `W/"v2"`})
+	assert.False(t, passed)
+
+	w, passed = newPP(mr, map[string]string{HeaderIfMatch: `*`})
+	assert.True(t, passed)
+
+	w, passed = newPP(mr, map[string]string{HeaderIfUnmodifiedSince: modTime.Format(http.TimeFormat)})
+	assert.True(t, passed)
+
+	w, passed = newPP(mr, map[string]string{HeaderIfUnmodifiedSince: modTime.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	// 要求必须携带条件
+	required := mr.RequirePrecondition()
+
+	w, passed = newPP(required, nil)
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w, passed = newPP(required, map[string]string{HeaderIfMatch: `"v2"`})
+	assert.True(t, passed)
+
+	// 原路由不受影响
+	w, passed = newPP(mr, nil)
+	assert.True(t, passed)
+}
+
+func TestPP_File(t *testing.T) {
+	f, err := ioutil.TempFile("", "mplus-*.txt")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+
+	content := "0123456789"
+	_, err = f.WriteString(content)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+		SetRequestHeaders(r, headers)
+
+		w := httptest.NewRecorder()
+		MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			PlusPlus(w, r).File(f.Name())
+		}).ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := serve(nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+	assert.Equal(t, "bytes", w.Header().Get(HeaderAcceptRanges))
+	assert.True(t, strings.HasPrefix(w.Header().Get(HeaderContentType), "text/plain"))
+	lastModified := w.Header().Get(HeaderLastModified)
+	assert.NotEmpty(t, lastModified)
+
+	w = serve(map[string]string{HeaderRange: "bytes=2-5"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+	assert.Equal(t, "bytes 2-5/10", w.Header().Get(HeaderContentRange))
+	assert.Equal(t, "4", w.Header().Get(HeaderContentLength))
+
+	w = serve(map[string]string{HeaderRange: "bytes=-3"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "789", w.Body.String())
+
+	w = serve(map[string]string{HeaderRange: "bytes=7-"})
+	assert.Equal(t, "789", w.Body.String())
+
+	// If-Range 命中时响应区间内容，否则响应完整内容
+	w = serve(map[string]string{HeaderRange: "bytes=0-0", HeaderIfRange: lastModified})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "0", w.Body.String())
+
+	w = serve(map[string]string{HeaderRange: "bytes=0-0", HeaderIfRange: `"other"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+
+	// 多个区间以 multipart/byteranges 响应
+	w = serve(map[string]string{HeaderRange: "bytes=0-1,-2"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get(HeaderContentLength))
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get(HeaderContentType))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for _, want := range [][2]string{{"bytes 0-1/10", "01"}, {"bytes 8-9/10", "89"}} {
+		part, err := mr.NextPart()
+		assert.Nil(t, err)
+		assert.Equal(t, want[0], part.Header.Get(HeaderContentRange))
+		assert.True(t, strings.HasPrefix(part.Header.Get(HeaderContentType), "text/plain"))
+
+		body, err := ioutil.ReadAll(part)
+		assert.Nil(t, err)
+		assert.Equal(t, want[1], string(body))
+	}
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// 区间总长度超出内容大小时响应完整内容
+	w = serve(map[string]string{HeaderRange: "bytes=0-8,1-9"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, content, w.Body.String())
+
+	w = serve(map[string]string{HeaderRange: "bytes=20-30"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */10", w.Header().Get(HeaderContentRange))
+
+	w = serve(map[string]string{HeaderIfModifiedSince: lastModified})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// 文件不存在
+	w = httptest.NewRecorder()
+	MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).File(f.Name() + ".not-exists")
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPP_Attachment(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderRange, "bytes=2-")
+
+	w := httptest.NewRecorder()
+	MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Attachment(strings.NewReader("a,b,c\n"), "报表 2019.csv", time.Time{})
+	}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "b,c\n", w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Header().Get(HeaderContentType), "text/csv"))
+	assert.Equal(t,
+		`attachment; filename="__ 2019.csv"; filename*=UTF-8''%E6%8A%A5%E8%A1%A8%202019.csv`,
+		w.Header().Get(HeaderContentDisposition))
+	assert.Empty(t, w.Header().Get(HeaderLastModified))
+
+	// 不支持 Seek 的 reader 忽略 Range 响应完整内容
+	modTime := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	w = httptest.NewRecorder()
+	MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Attachment(ioutil.NopCloser(strings.NewReader("a,b,c\n")), "report.csv", modTime)
+	}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "a,b,c\n", w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Header().Get(HeaderContentType), "text/csv"))
+	assert.Equal(t, modTime.Format(http.TimeFormat), w.Header().Get(HeaderLastModified))
+	assert.Empty(t, w.Header().Get(HeaderContentRange))
+
+	assert.Equal(t, `inline; filename="a\"b.txt"`, FormatContentDisposition(DispositionInline, `a"b.txt`))
+}
+
+func TestPP_Stream(t *testing.T) {
+	w := httptest.NewRecorder()
+	MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Stream(ioutil.NopCloser(strings.NewReader("stream")), "")
+	}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "stream", w.Body.String())
+	assert.Equal(t, MIMEStream, w.Header().Get(HeaderContentType))
+
+	// io.ReadSeeker 支持 Range
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+	SetRequestHeader(r, HeaderRange, "bytes=0-1")
+
+	w = httptest.NewRecorder()
+	MRote().HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		PlusPlus(w, r).Stream(strings.NewReader("stream"), MIMEPlain)
+	}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "st", w.Body.String())
+	assert.Equal(t, MIMEPlain, w.Header().Get(HeaderContentType))
+}
+
+func TestPP_CallbackByCode(t *testing.T) {
+	plain := NewErrCodeMessage(http.StatusConflict, 40911, "stock locked").AddI18Message(MSGLangZH, "库存已锁定")
+	Messages.Add(plain)
+
+	var lang string
+	Messages.Add(NewCallbackMessage(http.StatusConflict, 40912, "stock changed", func(w http.ResponseWriter, r *http.Request, m Message, respData interface{}) {
+		lang = m.Default()
+		JSON(w, r, respData, m.Status())
+	}).AddI18Message(MSGLangZH, "库存已变化"))
+
+	serve := func(app *App, code int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost:8080/stock?lang=zh", nil)
+		MRote().WithApp(app).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			PlusPlus(w, r).CallbackByCode(code, []int{1})
+		}).ServeHTTP(w, r)
+		return w
+	}
+
+	// 未注册回调且未设置信封时不响应任何内容
+	w := serve(NewApp(), plain.ErrCode())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// 未注册回调且设置了信封时以信封格式响应，消息按请求语言解析
+	envelopeApp := NewApp()
+	envelopeApp.SetEnvelope(DefaultEnvelope)
+	w = serve(envelopeApp, plain.ErrCode())
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code":40911,"message":"库存已锁定","data":[1]}`, w.Body.String())
+
+	// 注册了回调时无论是否设置信封均执行回调
+	for _, app := range []*App{NewApp(), envelopeApp} {
+		lang = ""
+		w = serve(app, 40912)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "库存已变化", lang)
+	}
+
+	// 未注册的错误码
+	w = serve(envelopeApp, 40919)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestPP_JSONEnvelope(t *testing.T) {
+	BeforeTest(false)
+	defer AfterTest(false)
+
+	SetEnvelope(NewEnvelope("errcode", "errmsg", "result"))
+	defer SetEnvelope(nil)
+
+	// 成功响应
+	w := httptest.NewRecorder()
+	PlusPlus(NewResponseWrite(w), httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)).JSONOK(map[string]string{"name": "tom"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"errcode":0,"errmsg":"OK","result":{"name":"tom"}}`, w.Body.String())
+
+	// 指定状态码
+	w = httptest.NewRecorder()
+	PlusPlus(NewResponseWrite(w), httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)).JSON(nil, http.StatusCreated)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"errcode":0,"errmsg":"Created","result":{}}`, w.Body.String())
+
+	// 未注册回调的错误码
+	m := NewErrCodeMessage(http.StatusConflict, 40901, "order exists")
+	Messages.Add(m)
+	w = httptest.NewRecorder()
+	PlusPlus(NewResponseWrite(w), httptest.NewRequest(http.MethodPost, "http://localhost:8080", nil)).CallbackByCode(m.ErrCode(), []int{1})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"errcode":40901,"errmsg":"order exists","result":[1]}`, w.Body.String())
+
+	// 按请求语言解析消息内容
+	r := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+	r = SetRequestLang(r, MSGLangZH)
+	assert.Equal(t, map[string]interface{}{"code": 40401, "message": "订单不存在", "data": EmptyRespData},
+		DefaultEnvelope(r, NewErrCodeMessage(http.StatusNotFound, 40401, "order not found").AddI18Message(MSGLangZH, "订单不存在"), nil))
+
+	// 取消信封
+	SetEnvelope(nil)
+	w = httptest.NewRecorder()
+	PlusPlus(NewResponseWrite(w), httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)).JSONOK(map[string]string{"name": "tom"})
+
+	assert.JSONEq(t, `{"name":"tom"}`, w.Body.String())
+}
@@ -2,7 +2,11 @@ package route
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/tangzixiang/mplus/app"
+	"github.com/tangzixiang/mplus/errs"
+	"github.com/tangzixiang/mplus/mhttp"
 	"github.com/tangzixiang/mplus/middleware"
 )
 
@@ -10,10 +14,23 @@ import (
 type mRote struct {
 	middlewares   []middleware.Middleware
 	before, after []http.Handler
+	app           *app.App
+	pattern       string
+	requirements  []requirement
+
+	// 路由级的请求状态回调及解析失败处理器，优先级高于全局注册的回调及处理器
+	statusMethods    *mhttp.StatusMethodHub
+	validateErrorHub map[errs.ValidateErrorType]errs.ValidateErrorFunc
 }
 
 type Route = mRote
 
+// requirement 路由通过 Require 或 RequireWith 声明的一组权限
+type requirement struct {
+	policy       middleware.AuthorizePolicy
+	requirements []string
+}
+
 // MRote 获取一个可复用型中间件路由实例
 func MRote() *mRote {
 	return new(mRote).UseHandlerMiddleware(middleware.PreHandler).Use(middleware.Pre) // 初始化上下文
@@ -39,6 +56,10 @@ func (mr *mRote) Handler(handler http.Handler) http.Handler {
 		handler = middleware.ThunkHandler(append([]http.Handler{handler}, mr.after...)...)
 	}
 
+	if len(mr.requirements) > 0 {
+		handler = mr.authorize(handler.ServeHTTP)
+	}
+
 	if len(mr.middlewares) > 0 {
 
 		for i := len(mr.middlewares); i > 0; i-- {
@@ -46,7 +67,7 @@ func (mr *mRote) Handler(handler http.Handler) http.Handler {
 		}
 	}
 
-	return handler
+	return mr.inject(handler.ServeHTTP)
 }
 
 // HandlerFunc 获取通过前置或后置请求处理器及中间件进行封装后的 HandlerFunc
@@ -64,6 +85,10 @@ func (mr *mRote) HandlerFunc(handler http.HandlerFunc) http.HandlerFunc {
 		handler = middleware.ThunkHandler(append([]http.Handler{handler}, mr.after...)...)
 	}
 
+	if len(mr.requirements) > 0 {
+		handler = mr.authorize(handler)
+	}
+
 	if len(mr.middlewares) > 0 {
 
 		for i := len(mr.middlewares); i > 0; i-- {
@@ -71,7 +96,7 @@ func (mr *mRote) HandlerFunc(handler http.HandlerFunc) http.HandlerFunc {
 		}
 	}
 
-	return handler
+	return mr.inject(handler)
 }
 
 // Use 使用 MiddlewareHandlerFunc 系列中间件
@@ -136,10 +161,184 @@ func (mr *mRote) Bind(validateData interface{}) *mRote {
 	return mr.Copy().BeforeHandler(middleware.Bind(validateData))
 }
 
+// RequirePrecondition 要求当前路由的写请求必须携带 If-Match 或 If-Unmodified-Since，配合 mplus.PP.Precondition 使用，返回的为当前路由的拷贝
+func (mr *mRote) RequirePrecondition() *mRote {
+	return mr.Copy().Before(func(w http.ResponseWriter, r *http.Request) { mhttp.RequirePrecondition(r) })
+}
+
+// WithApp 使用 a 中的配置处理当前路由的请求，返回的为当前路由的拷贝
+func (mr *mRote) WithApp(a *app.App) *mRote {
+	_mr := mr.Copy()
+	_mr.app = a
+	return _mr
+}
+
+// WithPattern 设置当前路由的路由模式，如 /orders/{id}，处理请求时可以通过 mhttp.RoutePattern 获取，返回的为当前路由的拷贝
+func (mr *mRote) WithPattern(pattern string) *mRote {
+	_mr := mr.Copy()
+	_mr.pattern = pattern
+	return _mr
+}
+
+// Pattern 获取当前路由的路由模式
+func (mr *mRote) Pattern() string {
+	return mr.pattern
+}
+
+// App 获取当前路由使用的 App，未设置时返回 app.Default
+func (mr *mRote) App() *app.App {
+	if mr.app != nil {
+		return mr.app
+	}
+
+	return app.Default
+}
+
+// HttpStatusMethod 注册当前路由的请求状态回调，优先级高于 mhttp.RegisterHttpStatusMethod 注册的回调，返回的为当前路由的拷贝
+func (mr *mRote) HttpStatusMethod(statusCode int, f mhttp.StatusMethodCallback) *mRote {
+	_mr := mr.Copy()
+	_mr.statusMethodHub().Register(statusCode, f)
+	return _mr
+}
+
+// HttpStatusClassMethod 注册当前路由的状态码类别回调，当前路由不存在精确匹配的回调时使用，返回的为当前路由的拷贝
+func (mr *mRote) HttpStatusClassMethod(class mhttp.StatusClass, f mhttp.StatusMethodCallback) *mRote {
+	_mr := mr.Copy()
+	_mr.statusMethodHub().RegisterClass(class, f)
+	return _mr
+}
+
+// HttpStatusFallbackMethod 注册当前路由的兜底请求状态回调，4xx/5xx 状态码在当前路由不存在精确匹配及类别匹配的回调时使用，返回的为当前路由的拷贝
+func (mr *mRote) HttpStatusFallbackMethod(f mhttp.StatusMethodCallback) *mRote {
+	_mr := mr.Copy()
+	_mr.statusMethodHub().RegisterFallback(f)
+	return _mr
+}
+
+func (mr *mRote) statusMethodHub() *mhttp.StatusMethodHub {
+	if mr.statusMethods == nil {
+		mr.statusMethods = mhttp.NewStatusMethodHub()
+	}
+
+	return mr.statusMethods
+}
+
+// ValidateErrorHandler 注册当前路由的解析失败处理器，优先级高于 errs.RegisterValidateErrorFunc 注册的处理器及全局 ValidateError 异常处理器，
+// 返回的为当前路由的拷贝
+func (mr *mRote) ValidateErrorHandler(errType errs.ValidateErrorType, fun errs.ValidateErrorFunc) *mRote {
+	_mr := mr.Copy()
+	if _mr.validateErrorHub == nil {
+		_mr.validateErrorHub = map[errs.ValidateErrorType]errs.ValidateErrorFunc{}
+	}
+
+	_mr.validateErrorHub[errType] = fun
+	return _mr
+}
+
+// inject 将当前路由的 App 配置、路由模式、声明的权限、请求状态回调及解析失败处理器注入请求上下文，
+// 设置了路由模式或声明了权限的路由同时记录至所使用的 App，见 app.App.Routes
+func (mr *mRote) inject(handler http.HandlerFunc) http.HandlerFunc {
+	requirements := mr.Requirements()
+	if mr.pattern != "" || len(requirements) != 0 {
+		mr.App().RegisterRoute(app.RouteInfo{Pattern: mr.pattern, Requirements: requirements})
+	}
+
+	if mr.app == nil && mr.pattern == "" && len(requirements) == 0 && mr.statusMethods == nil && mr.validateErrorHub == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if mr.app != nil {
+			ctx = mr.app.WithContext(ctx)
+		}
+
+		if mr.pattern != "" {
+			ctx = mhttp.WithRoutePattern(ctx, mr.pattern)
+		}
+
+		if len(requirements) != 0 {
+			ctx = mhttp.WithRouteRequirements(ctx, requirements)
+		}
+
+		if mr.statusMethods != nil {
+			ctx = mhttp.WithStatusMethods(ctx, mr.statusMethods)
+		}
+
+		if mr.validateErrorHub != nil {
+			ctx = errs.WithValidateErrorHub(ctx, mr.validateErrorHub)
+		}
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// Timeout 为当前路由设置请求超时时间，超时后根据 statusCode 以 504 或 503 响应，详见 middleware.Timeout，返回的为当前路由的拷贝
+func (mr *mRote) Timeout(timeout time.Duration, statusCode int) *mRote {
+	return mr.Copy().Use(middleware.Timeout(timeout, statusCode))
+}
+
+// IPFilter 为当前路由设置客户端 IP 的允许及拒绝列表，详见 middleware.IPFilter，返回的为当前路由的拷贝
+func (mr *mRote) IPFilter(allow, deny *middleware.IPList) *mRote {
+	return mr.Copy().Use(middleware.IPFilter(allow, deny))
+}
+
+// Require 声明当前路由要求的角色、权限范围或权限，通过 middleware.Authorize 以当前请求配置的默认授权策略校验请求的主体，
+// 未认证时以 401 响应，不满足时以 403 响应；多次调用时需同时满足每次声明的要求，返回的为当前路由的拷贝
+//
+// 授权校验总是在当前路由的全部中间件之后、前置请求处理器之前执行，因此与 Use 的调用顺序无关，
+// 如 MRote().Require("x").Use(mplus.AuthMiddleware(...)) 同样先认证后授权
+func (mr *mRote) Require(requirements ...string) *mRote {
+	return mr.RequireWith(nil, requirements...)
+}
+
+// RequireWith 与 Require 相同，但使用 policy 校验请求的主体，policy 为 nil 时使用当前请求配置的默认授权策略，返回的为当前路由的拷贝
+func (mr *mRote) RequireWith(policy middleware.AuthorizePolicy, requirements ...string) *mRote {
+	_mr := mr.Copy()
+	_mr.requirements = append(_mr.requirements, requirement{policy: policy, requirements: append([]string(nil), requirements...)})
+	return _mr
+}
+
+// Requirements 获取当前路由声明的全部角色、权限范围或权限，用于审计路由的访问控制
+func (mr *mRote) Requirements() []string {
+	var requirements []string
+	for _, req := range mr.requirements {
+		requirements = append(requirements, req.requirements...)
+	}
+
+	return requirements
+}
+
+// authorize 以当前路由声明的权限校验请求的主体，按声明顺序依次校验
+func (mr *mRote) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	for i := len(mr.requirements); i > 0; i-- {
+		req := mr.requirements[i-1]
+		handler = middleware.Authorize(req.policy, req.requirements...)(handler)
+	}
+
+	return handler
+}
+
 // Copy 获取一份当前配置的拷贝
 func (mr *mRote) Copy() *mRote {
 
-	_mr := &mRote{}
+	_mr := &mRote{app: mr.app, pattern: mr.pattern}
+
+	if mr.requirements != nil {
+		_mr.requirements = append([]requirement{}, mr.requirements...)
+	}
+
+	if mr.statusMethods != nil {
+		_mr.statusMethods = mr.statusMethods.Copy()
+	}
+
+	if mr.validateErrorHub != nil {
+		_mr.validateErrorHub = make(map[errs.ValidateErrorType]errs.ValidateErrorFunc, len(mr.validateErrorHub))
+		for errType, fun := range mr.validateErrorHub {
+			_mr.validateErrorHub[errType] = fun
+		}
+	}
 	for _, b := range mr.before {
 		_mr.before = append(_mr.before, b)
 	}
@@ -0,0 +1,132 @@
+package mplus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestRoute_HttpStatusMethod(t *testing.T) {
+	base := MRote().Bind(&User{})
+
+	webhook := base.HttpStatusMethod(http.StatusBadRequest, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		SetResponseHeader(w, HeaderContentType, ContentTypeText)
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte("bad request"))
+	})
+
+	api := base.ValidateErrorHandler(ErrBodyValidate, func(w http.ResponseWriter, r *http.Request, err error) {
+		JSON(w, r, map[string]interface{}{"error": "invalid body"}, http.StatusUnprocessableEntity)
+	})
+
+	handler := func(w http.ResponseWriter, r *http.Request) { PlusPlus(w, r).OK() }
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"Tom"}`))
+		SetRequestHeader(r, HeaderContentType, MIMEJSON)
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	webhook.HandlerFunc(handler).ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "bad request", w.Body.String())
+
+	w = httptest.NewRecorder()
+	api.HandlerFunc(handler).ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error":"invalid body"}`, w.Body.String())
+
+	// 拷贝的路由互不影响
+	w = httptest.NewRecorder()
+	api.Copy().HandlerFunc(handler).ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = httptest.NewRecorder()
+	base.HandlerFunc(handler).ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEqual(t, "bad request", w.Body.String())
+
+	// 状态方法同样使用路由级回调
+	w = httptest.NewRecorder()
+	MRote().HttpStatusMethod(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request, m Message, statusCode int) {
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte("missing"))
+	}).HandlerFunc(NotFound).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "missing", w.Body.String())
+}
+
+func TestRoute_Require(t *testing.T) {
+	app := NewApp()
+	authMiddleware := AuthMiddleware(BearerAuth("api", func(r *http.Request, token string) (*Principal, error) {
+		return &Principal{ID: token, Scopes: strings.Split(token, "+")}, nil
+	}))
+	auth := MRote().WithApp(app).Use(authMiddleware)
+
+	read := auth.WithPattern("/orders").Require("orders:read")
+	write := read.Require("orders:write")
+
+	assert.Equal(t, []string{"orders:read"}, read.Requirements())
+	assert.Equal(t, []string{"orders:read", "orders:write"}, write.Requirements())
+	assert.Empty(t, auth.Requirements())
+
+	var requirements []string
+	serve := func(route *Route, token string) int {
+		requirements = nil
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		r.Header.Set(HeaderAuthorization, "Bearer "+token)
+		route.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requirements = RouteRequirements(r)
+		}).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(read, "orders:read"))
+	assert.Equal(t, []string{"orders:read"}, requirements)
+	assert.Equal(t, http.StatusForbidden, serve(write, "orders:read"))
+	assert.Nil(t, requirements)
+	assert.Equal(t, http.StatusOK, serve(write, "orders:read+orders:write"))
+
+	// 未认证的请求
+	w := httptest.NewRecorder()
+	MRote().WithApp(app).Require("orders:read").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 授权校验与 Use 的调用顺序无关
+	reversed := MRote().WithApp(app).WithPattern("/orders/{id}").Require("orders:read").Use(authMiddleware)
+	assert.Equal(t, http.StatusOK, serve(reversed, "orders:read"))
+	assert.Equal(t, http.StatusForbidden, serve(reversed, "billing:read"))
+
+	// 路由级授权策略
+	anyScope := auth.WithPattern("/any").RequireWith(AnyScopePolicy, "orders:read", "orders:admin")
+	assert.Equal(t, http.StatusOK, serve(anyScope, "orders:admin"))
+	assert.Equal(t, http.StatusForbidden, serve(anyScope, "billing:read"))
+
+	// App 级默认授权策略
+	app.SetAuthorizePolicy(func(r *http.Request, principal *Principal, requirements []string) bool {
+		return principal != nil && principal.ID == "admin"
+	})
+
+	assert.Equal(t, http.StatusOK, serve(write, "admin"))
+	assert.Equal(t, http.StatusForbidden, serve(write, "orders:read+orders:write"))
+	assert.Equal(t, http.StatusForbidden, serve(anyScope, "admin"))
+	assert.Equal(t, http.StatusOK, serve(MRote().WithApp(NewApp()).Use(authMiddleware).Require("orders:read"), "orders:read"))
+
+	anyRoute := auth.Copy().Use(AuthorizeMiddleware(AnyScopePolicy, "orders:read", "orders:admin"))
+	assert.Equal(t, http.StatusOK, serve(anyRoute, "orders:admin"))
+	assert.Equal(t, http.StatusForbidden, serve(anyRoute, "billing:read"))
+
+	// 审计路由声明的权限
+	assert.Equal(t, []RouteInfo{
+		{Pattern: "/orders", Requirements: []string{"orders:read"}},
+		{Pattern: "/orders", Requirements: []string{"orders:read", "orders:write"}},
+		{Pattern: "", Requirements: []string{"orders:read"}},
+		{Pattern: "/orders/{id}", Requirements: []string{"orders:read"}},
+		{Pattern: "/any", Requirements: []string{"orders:read", "orders:admin"}},
+	}, app.Routes())
+}
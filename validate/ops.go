@@ -1,16 +1,88 @@
 package validate
 
-// strictJSONBodyCheck 是否严格校验 json 请求，若 StrictJSONBodyCheck 值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
-var strictJSONBodyCheck = false
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+type configKey struct{}
+
+// Config validate 的实例级配置，通过 WithConfig 注入请求上下文后当前请求将使用该配置，未注入时使用 DefaultConfig
+type Config struct {
+	lock sync.RWMutex
+	// strictJSONBodyCheck 是否严格校验 json 请求，若值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
+	strictJSONBodyCheck bool
+	validate            *validator.Validate
+}
+
+// DefaultConfig 默认配置，包级别的 SetStrictJSONBodyCheck 等函数均作用于该配置
+var DefaultConfig = NewConfig()
+
+// NewConfig 获取一个新的配置实例，未通过 SetValidator 设置校验器时使用全局的 Validate
+func NewConfig() *Config {
+	return new(Config)
+}
 
 // StrictJSONBodyCheck 获取 strictJSONBodyCheck
 // 若 strictJSONBodyCheck 值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
-func StrictJSONBodyCheck() bool {
-	return strictJSONBodyCheck
+func (c *Config) StrictJSONBodyCheck() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.strictJSONBodyCheck
 }
 
 // SetStrictJSONBodyCheck 设置 strictJSONBodyCheck
 // 若 strictJSONBodyCheck 值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
+func (c *Config) SetStrictJSONBodyCheck(b bool) {
+	c.lock.Lock()
+	c.strictJSONBodyCheck = b
+	c.lock.Unlock()
+}
+
+// SetValidator 设置校验器，为 nil 时使用全局的 Validate
+func (c *Config) SetValidator(v *validator.Validate) {
+	c.lock.Lock()
+	c.validate = v
+	c.lock.Unlock()
+}
+
+// Validator 获取校验器
+func (c *Config) Validator() *validator.Validate {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.validate != nil {
+		return c.validate
+	}
+
+	return Validate
+}
+
+// WithConfig 将配置注入至 ctx
+func WithConfig(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf 获取当前请求使用的配置，未注入时返回 DefaultConfig
+func ConfigOf(r *http.Request) *Config {
+	if c, ok := r.Context().Value(configKey{}).(*Config); ok && c != nil {
+		return c
+	}
+
+	return DefaultConfig
+}
+
+// StrictJSONBodyCheck 获取 DefaultConfig 的 strictJSONBodyCheck
+// 若 strictJSONBodyCheck 值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
+func StrictJSONBodyCheck() bool {
+	return DefaultConfig.StrictJSONBodyCheck()
+}
+
+// SetStrictJSONBodyCheck 设置 DefaultConfig 的 strictJSONBodyCheck
+// 若 strictJSONBodyCheck 值为 true 且请求为 json 请求，则读取到空的数据将抛出异常
 func SetStrictJSONBodyCheck(b bool) {
-	strictJSONBodyCheck = b
+	DefaultConfig.SetStrictJSONBodyCheck(b)
 }
@@ -64,7 +64,7 @@ func CheckValidateData(r *http.Request, validateData interface{}, vr *ValidateRe
 func bindValidate(r *http.Request, obj interface{}, vr *ValidateResult) {
 
 	// 2. tag 规则校验
-	if err := Validate.Struct(obj); err != nil {
+	if err := ConfigOf(r).Validator().Struct(obj); err != nil {
 		vr.Err = errs.ValidateErrorWrap(err, errs.ErrBodyValidate)
 		return
 	}
@@ -93,6 +93,13 @@ func parse(r *http.Request, vr *ValidateResult) {
 
 	switch r.Method {
 	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete /*delete 请求可以有主体 https://developer.mozilla.org/en-US/docs/Web/HTTP/Methods/DELETE */ : // 考虑做成动态的
+
+		// 解压请求体
+		if err := mhttp.DecodeRequestBody(r); err != nil {
+			vr.Err = decodeBodyErr(err)
+			return
+		}
+
 		switch vr.MediaType {
 		case mime.MIMEPOSTForm:
 			body := mhttp.DumpRequestPure(r)
@@ -120,7 +127,7 @@ func parse(r *http.Request, vr *ValidateResult) {
 			r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		case mime.MIMEMultipartPOSTForm:
 			body := mhttp.DumpRequestPure(r)
-			if err := r.ParseMultipartForm(mhttp.DefaultMemorySize()); err != nil {
+			if err := r.ParseMultipartForm(mhttp.ConfigOf(r).DefaultMemorySize()); err != nil {
 				vr.Err = errs.ValidateErrorWrap(err, errs.ErrBodyParse)
 			}
 
@@ -143,10 +150,15 @@ func parse(r *http.Request, vr *ValidateResult) {
 
 			r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		case mime.MIMEJSON:
-			body := mhttp.DumpRequestPure(r)
+			body, err := mhttp.ReadRequestBody(r)
+			if err != nil {
+				vr.Err = errs.ValidateErrorWrap(err, errs.ErrBodyRead)
+				return
+			}
+
 			if len(body) != 0 {
 				vr.BodyBytes = body
-			} else if StrictJSONBodyCheck() { // 是否严格校验 json body
+			} else if ConfigOf(r).StrictJSONBodyCheck() { // 是否严格校验 json body
 				vr.Err = errs.ValidateErrorWrap(errors.New("body empty"), errs.ErrBodyRead)
 			}
 
@@ -179,6 +191,18 @@ func parse(r *http.Request, vr *ValidateResult) {
 	}
 }
 
+// decodeBodyErr 将请求体解压异常转换为对应的校验异常
+func decodeBodyErr(err error) error {
+	switch err {
+	case mhttp.ErrContentEncoding:
+		return errs.ValidateErrorWrap(err, errs.ErrMediaType)
+	case mhttp.ErrBodyTooLarge:
+		return errs.ValidateErrorWrap(err, errs.ErrBodyTooLarge)
+	default:
+		return errs.ValidateErrorWrap(err, errs.ErrBodyRead)
+	}
+}
+
 func decodeTo(r *http.Request, obj interface{}, vr *ValidateResult) {
 	switch r.Method {
 	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete /*delete 请求可以有主体 https://developer.mozilla.org/zh-CN/docs/Web/HTTP/Methods/DELETE */ : // 考虑做成动态的
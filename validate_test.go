@@ -2,6 +2,8 @@ package mplus
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -136,3 +138,106 @@ func TestValidatorStandErrMsg(t *testing.T) {
 	errMsg := "Key: 'body.Size' Error:Field validation for 'Size' failed on the 'required' tag"
 	assert.Equal(t, errMsg, recorder.Body.String())
 }
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, gw.Close())
+
+	return buf.Bytes()
+}
+
+func TestParseValidateGzipBody(t *testing.T) {
+	jsonStr := `{"name":"Tom","age":50,"gender":"male","email":"10086@fox.com"}`
+
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", bytes.NewReader(gzipBytes(t, []byte(jsonStr))))
+	SetRequestHeader(request, HeaderContentType, MIMEJSON)
+	SetRequestHeader(request, HeaderContentEncoding, "gzip")
+
+	var vr ValidateResult
+	var user User
+
+	Parse(request, &vr)
+	assert.Nil(t, vr.Err)
+	assert.Equal(t, jsonStr, string(vr.BodyBytes))
+	assert.Equal(t, "", GetHeader(request, HeaderContentEncoding))
+
+	DecodeTo(request, &user, &vr)
+	assert.Nil(t, vr.Err)
+	assert.Equal(t, "Tom", user.Name)
+}
+
+func TestParseValidateDeflateBody(t *testing.T) {
+	content := NewQuery().AddPairs("name", "tom", "age", "18").Encode()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write([]byte(content))
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", &buf)
+	SetRequestHeader(request, HeaderContentType, MIMEPOSTForm)
+	SetRequestHeader(request, HeaderContentEncoding, "deflate")
+
+	var vr ValidateResult
+
+	Parse(request, &vr)
+	assert.Nil(t, vr.Err)
+	assert.Equal(t, "tom", vr.BodyValues.Get("name"))
+}
+
+func TestParseValidateErrContentEncoding(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(`{}`))
+	SetRequestHeader(request, HeaderContentType, MIMEJSON)
+	SetRequestHeader(request, HeaderContentEncoding, "br")
+
+	var vr ValidateResult
+
+	Parse(request, &vr)
+	assert.NotNil(t, vr.Err)
+	assert.Equal(t, ErrMediaType, errors.Cause(vr.Err).(ValidateError).Type())
+
+	// 默认处理器响应 415
+	r := request.WithContext(NewContext(request.Context()))
+	SetRequestHeader(r, HeaderContentEncoding, "br")
+	w := NewResponseWrite(httptest.NewRecorder())
+
+	Bind((*User)(nil)).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnsupportedMediaType, GetHTTPRespStatus(w))
+}
+
+func TestParseValidateErrBodyDecompress(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(`not gzip`))
+	SetRequestHeader(request, HeaderContentType, MIMEJSON)
+	SetRequestHeader(request, HeaderContentEncoding, "gzip")
+
+	var vr ValidateResult
+
+	Parse(request, &vr)
+	assert.NotNil(t, vr.Err)
+	assert.Equal(t, ErrBodyRead, errors.Cause(vr.Err).(ValidateError).Type())
+
+	// 解压失败时保留原始内容
+	assert.Equal(t, "not gzip", DumpRequest(request))
+}
+
+func TestParseValidateErrBodyTooLarge(t *testing.T) {
+	size := MaxDecompressSize()
+	SetMaxDecompressSize(16)
+	defer SetMaxDecompressSize(size)
+
+	body := gzipBytes(t, bytes.Repeat([]byte("a"), 1024))
+	request := httptest.NewRequest(http.MethodPost, "http://localhost", bytes.NewReader(body))
+	SetRequestHeader(request, HeaderContentType, MIMEJSON)
+	SetRequestHeader(request, HeaderContentEncoding, "gzip")
+
+	var vr ValidateResult
+
+	Parse(request, &vr)
+	assert.NotNil(t, vr.Err)
+	assert.Equal(t, ErrBodyTooLarge, errors.Cause(vr.Err).(ValidateError).Type())
+}